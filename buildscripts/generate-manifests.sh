@@ -63,6 +63,21 @@ rm deploy/yamls/local.openebs.io_lvmsnapshots.yaml
 
 echo '
 
+##############################################
+###########                       ############
+###########  LVMSnapshotGroup CRD ############
+###########                       ############
+##############################################
+
+# LVMSnapshotGroup CRD is autogenerated via `make manifests` command.
+# Do the modification in the code and run the `make manifests` command
+# to generate the CRD definition' > deploy/yamls/lvmsnapshotgroup-crd.yaml
+
+cat deploy/yamls/local.openebs.io_lvmsnapshotgroups.yaml >> deploy/yamls/lvmsnapshotgroup-crd.yaml
+rm deploy/yamls/local.openebs.io_lvmsnapshotgroups.yaml
+
+echo '
+
 ##############################################
 ###########                       ############
 ###########     LVMNode CRD       ############
@@ -95,6 +110,9 @@ cat deploy/yamls/lvmvolume-crd.yaml >> deploy/lvm-operator.yaml
 # Add LVMSnapshot v1alpha1 CRDs to the Operator yaml
 cat deploy/yamls/lvmsnapshot-crd.yaml >> deploy/lvm-operator.yaml
 
+# Add LVMSnapshotGroup v1alpha1 CRDs to the Operator yaml
+cat deploy/yamls/lvmsnapshotgroup-crd.yaml >> deploy/lvm-operator.yaml
+
 # Add LVMNode v1alpha1 CRDs to the Operator yaml
 cat deploy/yamls/lvmnode-crd.yaml >> deploy/lvm-operator.yaml
 
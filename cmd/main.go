@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
@@ -134,6 +135,47 @@ func main() {
 		&config.NodeControllerPollingInterval, "node-polling-interval", 60, "The interval, in seconds, between node polling.",
 	)
 
+	cmd.PersistentFlags().BoolVar(
+		&config.LeaderElection, "leader-election", false,
+		"Enables leader election among the controller plugin replicas.",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&config.LeaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace where the leader election lease is created, defaults to the LVM namespace.",
+	)
+
+	cmd.PersistentFlags().DurationVar(
+		&config.LeaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"Duration for which the standby replicas wait before taking over the leadership.",
+	)
+
+	cmd.PersistentFlags().DurationVar(
+		&config.LeaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"Duration for which the leader retries renewing the leadership before giving it up.",
+	)
+
+	cmd.PersistentFlags().DurationVar(
+		&config.LeaderElectionRetryPeriod, "leader-election-retry-period", 5*time.Second,
+		"Duration the replicas wait between the attempts to acquire or renew the leadership.",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&config.WebhookListenAddress, "webhook-listen-address", "",
+		"The TCP network address where the admission webhook validating the storage and snapshot classes listens (example: `:9443`). "+
+			"The default is empty string, which means the webhook is disabled.",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&config.WebhookTLSCertFile, "webhook-tls-cert-file", "/etc/webhook/certs/tls.crt",
+		"Path of the TLS certificate served by the admission webhook.",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&config.WebhookTLSKeyFile, "webhook-tls-key-file", "/etc/webhook/certs/tls.key",
+		"Path of the private key of the TLS certificate served by the admission webhook.",
+	)
+
 	err := cmd.Execute()
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%s", err.Error())
@@ -165,6 +207,9 @@ func run(config *config.Config) {
 		lvm.SetIORateLimits(config)
 	}
 
+	// scope the CRs watched and created to this driver instance
+	lvm.DriverName = config.DriverName
+
 	err := driver.New(config).Run()
 	if err != nil {
 		log.Fatalln(err)
@@ -112,6 +112,38 @@ type VolumeGroup struct {
 	// int and string for its value:
 	// [-1: "", 0: "normal", 1: "contiguous", 2: "cling", 3: "anywhere", 4: "inherited"]
 	AllocationPolicy int `json:"allocationPolicy"`
+
+	// ThinPools specifies the thin pools present in the volume group.
+	ThinPools []ThinPool `json:"thinPools,omitempty"`
+}
+
+// ThinPool specifies attributes of a given thin pool exists in a vg.
+type ThinPool struct {
+	// Name of the thin pool logical volume.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Size specifies the total data size of the thin pool.
+	// +kubebuilder:validation:Required
+	Size resource.Quantity `json:"size"`
+
+	// Free specifies the data size of the thin pool which is not
+	// used yet by the thin volumes and snapshots.
+	// +kubebuilder:validation:Required
+	Free resource.Quantity `json:"free"`
+
+	// VirtualSize specifies the sum of the sizes of the thin volumes
+	// and snapshots allocated from the thin pool.
+	// +kubebuilder:validation:Required
+	VirtualSize resource.Quantity `json:"virtualSize"`
+
+	// MetadataSize specifies the size of the metadata of the thin pool.
+	MetadataSize resource.Quantity `json:"metadataSize,omitempty"`
+
+	// MetadataFree specifies the size of the metadata of the
+	// thin pool which is not used yet.
+	MetadataFree resource.Quantity `json:"metadataFree,omitempty"`
 }
 
 // LVMNodeList is a collection of LVMNode resources
@@ -65,4 +65,13 @@ type LVMSnapshotList struct {
 // SnapStatus string that reflects if the snapshot was created successfully
 type SnapStatus struct {
 	State string `json:"state,omitempty"`
+
+	// CreationTime is the time when the snapshot logical
+	// volume has been created on the node.
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// OriginSize is the size in bytes of the volume at the time
+	// the snapshot has been taken, which is the minimum size of
+	// the volume restored from the snapshot.
+	OriginSize string `json:"originSize,omitempty"`
 }
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=lvmsnapshotgroup
+
+// LVMSnapshotGroup represents a crash consistent group of LVM Snapshots
+// of the lvm volumes present on a node
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=lvmsnapgroup
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.ownerNodeID`,description="Node where the snapshots are created"
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.state`,description="Status of the snapshot group"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description="Age of the snapshot group"
+type LVMSnapshotGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LVMSnapshotGroupSpec `json:"spec"`
+	Status SnapGroupStatus      `json:"status,omitempty"`
+}
+
+// LVMSnapshotGroupSpec defines LVMSnapshotGroup spec
+type LVMSnapshotGroupSpec struct {
+	// OwnerNodeID is the Node ID where all the member volumes of the group
+	// are present which is where the snapshots are provisioned.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	OwnerNodeID string `json:"ownerNodeID"`
+
+	// Volumes specifies the names of the LVMVolumes which are snapshotted
+	// together at the same point in time.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:Required
+	Volumes []string `json:"volumes"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=lvmsnapshotgroups
+
+// LVMSnapshotGroupList is a list of LVMSnapshotGroup resources
+type LVMSnapshotGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []LVMSnapshotGroup `json:"items"`
+}
+
+// SnapGroupStatus reflects whether the snapshots of all the member volumes
+// of the group were created successfully
+type SnapGroupStatus struct {
+	// State specifies the current state of the snapshot group. The state
+	// "Pending" means that the snapshots have not been created yet. The
+	// state "Ready" means that the snapshots of all the member volumes have
+	// been created and are ready for use. "Failed" means that the group
+	// could not be snapshotted and will not be retried by the node agent.
+	// +kubebuilder:validation:Enum=Pending;Ready;Failed
+	State string `json:"state,omitempty"`
+
+	// Snapshots specifies the names of the member LVMSnapshots of the group.
+	Snapshots []string `json:"snapshots,omitempty"`
+
+	// Error denotes the error occurred while creating the snapshots.
+	// It should only be set when State becomes Failed.
+	Error string `json:"error,omitempty"`
+}
@@ -64,6 +64,12 @@ type VolumeInfo struct {
 	// +kubebuilder:validation:Required
 	VolGroup string `json:"volGroup"`
 
+	// LVName specifies the name of the logical volume in the volume group.
+	// The name of the LVMVolume is used as the logical volume name if it
+	// is not set. LVName can not be edited after the volume has been
+	// provisioned.
+	LVName string `json:"lvName,omitempty"`
+
 	// VgPattern specifies the regex to choose volume groups where volume
 	// needs to be created.
 	// +kubebuilder:validation:Required
@@ -87,6 +93,65 @@ type VolumeInfo struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Enum=yes;no
 	ThinProvision string `json:"thinProvision,omitempty"`
+
+	// SnapName specifies the name of the LVMSnapshot from which the volume
+	// has been restored. The volume is created in the same volume group
+	// where the snapshot is present.
+	SnapName string `json:"snapName,omitempty"`
+
+	// SourceVolume specifies the name of the LVMVolume from which the volume
+	// has been cloned. The volume is created in the same volume group
+	// where the source volume is present.
+	SourceVolume string `json:"sourceVolume,omitempty"`
+
+	// Permission specifies the access permission of the logical volume,
+	// "rw" for read-write and "r" for read-only. It can be modified after
+	// the volume has been provisioned.
+	// +kubebuilder:validation:Enum=rw;r
+	Permission string `json:"permission,omitempty"`
+
+	// ErrorWhenFull specifies whether the thin pool of the volume returns
+	// errors immediately when it is full, instead of queuing the IOs until
+	// it gets extended. It applies to the whole thin pool, so it is not
+	// applied if another volume of the pool has a different one. It can
+	// be modified after the volume has been provisioned.
+	// +kubebuilder:validation:Enum=yes;no
+	ErrorWhenFull string `json:"errorWhenFull,omitempty"`
+
+	// ThinPoolThreshold specifies the percentage of the data or metadata
+	// usage of a thin pool from which no more thin volumes are allocated
+	// from it. It applies only to the thin provisioned volumes.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	ThinPoolThreshold int32 `json:"thinPoolThreshold,omitempty"`
+
+	// IOLimits specifies the IO limits of the volume overriding the ones
+	// derived from the per GB rates configured for its volume group.
+	// It can be modified after the volume has been provisioned.
+	IOLimits *VolumeIOLimits `json:"ioLimits,omitempty"`
+
+	// DeletionPolicy specifies how the deletion of the volume is handled
+	// when it still has snapshots. "Block" rejects the deletion until the
+	// snapshots are deleted and "Cascade" deletes the snapshots along with
+	// the volume.
+	// +kubebuilder:validation:Enum=Block;Cascade
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+// VolumeIOLimits specifies the IO limits of a volume. A zero value
+// means there is no limit.
+type VolumeIOLimits struct {
+	// ReadIOPS specifies the read IOs per second limit.
+	ReadIOPS uint64 `json:"readIOPS,omitempty"`
+
+	// WriteIOPS specifies the write IOs per second limit.
+	WriteIOPS uint64 `json:"writeIOPS,omitempty"`
+
+	// ReadBPS specifies the read bytes per second limit.
+	ReadBPS uint64 `json:"readBPS,omitempty"`
+
+	// WriteBPS specifies the write bytes per second limit.
+	WriteBPS uint64 `json:"writeBPS,omitempty"`
 }
 
 // VolStatus string that specifies the current state of the volume provisioning request.
@@ -102,6 +167,68 @@ type VolStatus struct {
 	// Error denotes the error occurred during provisioning/expanding a volume.
 	// Error field should only be set when State becomes Failed.
 	Error *VolumeError `json:"error,omitempty"`
+
+	// Progress denotes the percentage of the content copied from the source
+	// snapshot or volume while the volume is being restored or cloned.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Progress int32 `json:"progress,omitempty"`
+
+	// Condition denotes the health of the volume as observed by the
+	// node agent on the node where the volume has been provisioned.
+	Condition *VolumeCondition `json:"condition,omitempty"`
+
+	// Rollback denotes the progress of the rollback of the volume to
+	// one of its snapshots.
+	Rollback *VolumeRollback `json:"rollback,omitempty"`
+
+	// Attempts records the failed attempts to provision the volume.
+	// An attempt is recorded when the failed volume is rescheduled.
+	Attempts []VolumeAttempt `json:"attempts,omitempty"`
+
+	// PublishedPaths lists the target paths where the volume is
+	// published by the node agent on the node of the volume.
+	PublishedPaths []string `json:"publishedPaths,omitempty"`
+}
+
+// VolumeAttempt specifies a failed attempt to provision a volume.
+type VolumeAttempt struct {
+	// Node specifies the node where the volume could not be provisioned.
+	Node string `json:"node"`
+
+	// Error denotes the error occurred while provisioning the volume.
+	Error *VolumeError `json:"error,omitempty"`
+
+	// Time specifies when the volume was rescheduled after the failure.
+	Time metav1.Time `json:"time,omitempty"`
+}
+
+// VolumeRollback specifies the progress of the rollback of a volume.
+type VolumeRollback struct {
+	// Snapshot specifies the name of the LVMSnapshot to which the
+	// volume is being rolled back.
+	Snapshot string `json:"snapshot"`
+
+	// State specifies the current state of the rollback. The state
+	// "Pending" means that the rollback is waiting for the volume to be
+	// unpublished. The state "Merging" means that the snapshot is being
+	// merged into the volume. "Completed" means that the volume has been
+	// rolled back and "Failed" means that the rollback has been failed
+	// and will not be retried by node agent controller.
+	// +kubebuilder:validation:Enum=Pending;Merging;Completed;Failed
+	State string `json:"state"`
+
+	// Message describes the reason of the current state of the rollback.
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeCondition specifies the health of the volume.
+type VolumeCondition struct {
+	// Abnormal denotes whether the volume is in an abnormal state.
+	Abnormal bool `json:"abnormal"`
+
+	// Message describes the reason of the abnormal state of the volume.
+	Message string `json:"message,omitempty"`
 }
 
 // VolumeError specifies the error occurred during volume provisioning.
@@ -120,4 +247,7 @@ const (
 	// InsufficientCapacity represent lvm vg doesn't
 	// have enough capacity to fit the lv request.
 	InsufficientCapacity VolumeErrorCode = "InsufficientCapacity"
+	// VGNotFound represent there is no lvm vg on
+	// the node matching the vg pattern of the volume.
+	VGNotFound VolumeErrorCode = "VGNotFound"
 )
@@ -74,6 +74,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&LVMVolumeList{},
 		&LVMSnapshot{},
 		&LVMSnapshotList{},
+		&LVMSnapshotGroup{},
+		&LVMSnapshotGroupList{},
 		&LVMNode{},
 		&LVMNodeList{},
 	)
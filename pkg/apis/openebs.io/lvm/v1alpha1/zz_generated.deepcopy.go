@@ -97,7 +97,7 @@ func (in *LVMSnapshot) DeepCopyInto(out *LVMSnapshot) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -119,6 +119,88 @@ func (in *LVMSnapshot) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LVMSnapshotGroup) DeepCopyInto(out *LVMSnapshotGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LVMSnapshotGroup.
+func (in *LVMSnapshotGroup) DeepCopy() *LVMSnapshotGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(LVMSnapshotGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LVMSnapshotGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LVMSnapshotGroupList) DeepCopyInto(out *LVMSnapshotGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LVMSnapshotGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LVMSnapshotGroupList.
+func (in *LVMSnapshotGroupList) DeepCopy() *LVMSnapshotGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(LVMSnapshotGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LVMSnapshotGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LVMSnapshotGroupSpec) DeepCopyInto(out *LVMSnapshotGroupSpec) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LVMSnapshotGroupSpec.
+func (in *LVMSnapshotGroupSpec) DeepCopy() *LVMSnapshotGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LVMSnapshotGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LVMSnapshotList) DeepCopyInto(out *LVMSnapshotList) {
 	*out = *in
@@ -173,7 +255,7 @@ func (in *LVMVolume) DeepCopyInto(out *LVMVolume) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -229,9 +311,34 @@ func (in *LVMVolumeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapGroupStatus) DeepCopyInto(out *SnapGroupStatus) {
+	*out = *in
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapGroupStatus.
+func (in *SnapGroupStatus) DeepCopy() *SnapGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SnapStatus) DeepCopyInto(out *SnapStatus) {
 	*out = *in
+	if in.CreationTime != nil {
+		in, out := &in.CreationTime, &out.CreationTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -245,6 +352,27 @@ func (in *SnapStatus) DeepCopy() *SnapStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThinPool) DeepCopyInto(out *ThinPool) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	out.Free = in.Free.DeepCopy()
+	out.VirtualSize = in.VirtualSize.DeepCopy()
+	out.MetadataSize = in.MetadataSize.DeepCopy()
+	out.MetadataFree = in.MetadataFree.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThinPool.
+func (in *ThinPool) DeepCopy() *ThinPool {
+	if in == nil {
+		return nil
+	}
+	out := new(ThinPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolStatus) DeepCopyInto(out *VolStatus) {
 	*out = *in
@@ -253,6 +381,28 @@ func (in *VolStatus) DeepCopyInto(out *VolStatus) {
 		*out = new(VolumeError)
 		**out = **in
 	}
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(VolumeCondition)
+		**out = **in
+	}
+	if in.Rollback != nil {
+		in, out := &in.Rollback, &out.Rollback
+		*out = new(VolumeRollback)
+		**out = **in
+	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]VolumeAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PublishedPaths != nil {
+		in, out := &in.PublishedPaths, &out.PublishedPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -266,6 +416,44 @@ func (in *VolStatus) DeepCopy() *VolStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeAttempt) DeepCopyInto(out *VolumeAttempt) {
+	*out = *in
+	if in.Error != nil {
+		in, out := &in.Error, &out.Error
+		*out = new(VolumeError)
+		**out = **in
+	}
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeAttempt.
+func (in *VolumeAttempt) DeepCopy() *VolumeAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeCondition) DeepCopyInto(out *VolumeCondition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeCondition.
+func (in *VolumeCondition) DeepCopy() *VolumeCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeError) DeepCopyInto(out *VolumeError) {
 	*out = *in
@@ -289,6 +477,13 @@ func (in *VolumeGroup) DeepCopyInto(out *VolumeGroup) {
 	out.Free = in.Free.DeepCopy()
 	out.MetadataFree = in.MetadataFree.DeepCopy()
 	out.MetadataSize = in.MetadataSize.DeepCopy()
+	if in.ThinPools != nil {
+		in, out := &in.ThinPools, &out.ThinPools
+		*out = make([]ThinPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -302,9 +497,30 @@ func (in *VolumeGroup) DeepCopy() *VolumeGroup {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeIOLimits) DeepCopyInto(out *VolumeIOLimits) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeIOLimits.
+func (in *VolumeIOLimits) DeepCopy() *VolumeIOLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeIOLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeInfo) DeepCopyInto(out *VolumeInfo) {
 	*out = *in
+	if in.IOLimits != nil {
+		in, out := &in.IOLimits, &out.IOLimits
+		*out = new(VolumeIOLimits)
+		**out = **in
+	}
 	return
 }
 
@@ -317,3 +533,19 @@ func (in *VolumeInfo) DeepCopy() *VolumeInfo {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeRollback) DeepCopyInto(out *VolumeRollback) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeRollback.
+func (in *VolumeRollback) DeepCopy() *VolumeRollback {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeRollback)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -100,6 +100,23 @@ func (b *Builder) WithName(name string) *Builder {
 	return b
 }
 
+// WithLabels merges existing labels if any
+// with the ones that are provided here
+func (b *Builder) WithLabels(labels map[string]string) *Builder {
+	if len(labels) == 0 {
+		return b
+	}
+
+	if b.node.Object.Labels == nil {
+		b.node.Object.Labels = map[string]string{}
+	}
+
+	for key, value := range labels {
+		b.node.Object.Labels[key] = value
+	}
+	return b
+}
+
 // WithVolumeGroups sets the volume groups of LVMNode
 func (b *Builder) WithVolumeGroups(vgs []apis.VolumeGroup) *Builder {
 	b.node.Object.VolumeGroups = vgs
@@ -18,6 +18,8 @@ package snapbuilder
 
 import (
 	"github.com/openebs/lib-csi/pkg/common/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
 )
 
@@ -108,6 +110,13 @@ func (b *Builder) WithFinalizer(finalizer []string) *Builder {
 	return b
 }
 
+// WithOwnerReference sets the owner of the LVMSnapshot, the snapshot
+// gets garbage collected once its owner is deleted
+func (b *Builder) WithOwnerReference(ref metav1.OwnerReference) *Builder {
+	b.snap.Object.OwnerReferences = append(b.snap.Object.OwnerReferences, ref)
+	return b
+}
+
 // WithSnapshotSize sets the SnapSize of lvm snapshot
 func (b *Builder) WithSnapSize(capacity string) *Builder {
 	if capacity == "" {
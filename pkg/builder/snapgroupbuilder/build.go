@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapgroupbuilder
+
+import (
+	"github.com/openebs/lib-csi/pkg/common/errors"
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+)
+
+// Builder is the builder object for LVMSnapshotGroup
+type Builder struct {
+	group *LVMSnapshotGroup
+	errs  []error
+}
+
+// NewBuilder returns new instance of Builder
+func NewBuilder() *Builder {
+	return &Builder{
+		group: &LVMSnapshotGroup{
+			Object: &apis.LVMSnapshotGroup{},
+		},
+	}
+}
+
+// BuildFrom returns new instance of Builder
+// from the provided api instance
+func BuildFrom(group *apis.LVMSnapshotGroup) *Builder {
+	if group == nil {
+		b := NewBuilder()
+		b.errs = append(
+			b.errs,
+			errors.New("failed to build snap group object: nil group"),
+		)
+		return b
+	}
+	return &Builder{
+		group: &LVMSnapshotGroup{
+			Object: group,
+		},
+	}
+}
+
+// WithName sets the name of LVMSnapshotGroup
+func (b *Builder) WithName(name string) *Builder {
+	if name == "" {
+		b.errs = append(
+			b.errs,
+			errors.New(
+				"failed to build lvm snap group object: missing name",
+			),
+		)
+		return b
+	}
+	b.group.Object.Name = name
+	return b
+}
+
+// WithLabels merges existing labels if any
+// with the ones that are provided here
+func (b *Builder) WithLabels(labels map[string]string) *Builder {
+	if len(labels) == 0 {
+		return b
+	}
+
+	if b.group.Object.Labels == nil {
+		b.group.Object.Labels = map[string]string{}
+	}
+
+	for key, value := range labels {
+		b.group.Object.Labels[key] = value
+	}
+	return b
+}
+
+// WithOwnerNode sets owner node for the LVMSnapshotGroup where the
+// snapshots should be provisioned
+func (b *Builder) WithOwnerNode(host string) *Builder {
+	b.group.Object.Spec.OwnerNodeID = host
+	return b
+}
+
+// WithVolumes sets the member volumes of the LVMSnapshotGroup
+func (b *Builder) WithVolumes(volumes []string) *Builder {
+	if len(volumes) == 0 {
+		b.errs = append(
+			b.errs,
+			errors.New(
+				"failed to build lvm snap group object: missing volumes",
+			),
+		)
+		return b
+	}
+	b.group.Object.Spec.Volumes = volumes
+	return b
+}
+
+// WithSnapGroupStatus sets the state of the LVMSnapshotGroup
+func (b *Builder) WithSnapGroupStatus(state string) *Builder {
+	b.group.Object.Status.State = state
+	return b
+}
+
+// WithSnapshots sets the member snapshots of the LVMSnapshotGroup
+func (b *Builder) WithSnapshots(snapshots []string) *Builder {
+	b.group.Object.Status.Snapshots = snapshots
+	return b
+}
+
+// WithError sets the error occurred while creating the snapshots
+func (b *Builder) WithError(message string) *Builder {
+	b.group.Object.Status.Error = message
+	return b
+}
+
+// Build returns LVMSnapshotGroup API object
+func (b *Builder) Build() (*apis.LVMSnapshotGroup, error) {
+	if len(b.errs) > 0 {
+		return nil, errors.Errorf("%+v", b.errs)
+	}
+
+	return b.group.Object, nil
+}
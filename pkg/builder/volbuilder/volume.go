@@ -139,6 +139,85 @@ func (b *Builder) WithThinProvision(thinProvision string) *Builder {
 	return b
 }
 
+// WithThinPoolThreshold sets the usage percentage of the thin
+// pools from which the thin volume is no more allocated
+func (b *Builder) WithThinPoolThreshold(threshold int32) *Builder {
+	b.volume.Object.Spec.ThinPoolThreshold = threshold
+	return b
+}
+
+// WithDeletionPolicy sets how the deletion of the volume
+// is handled when it still has snapshots
+func (b *Builder) WithDeletionPolicy(policy string) *Builder {
+	b.volume.Object.Spec.DeletionPolicy = policy
+	return b
+}
+
+// WithLVName sets the name of the logical volume, the name
+// of the LVMVolume is used if it is not set
+func (b *Builder) WithLVName(lvName string) *Builder {
+	b.volume.Object.Spec.LVName = lvName
+	return b
+}
+
+// WithPermission sets the access permission of the logical volume
+func (b *Builder) WithPermission(permission string) *Builder {
+	b.volume.Object.Spec.Permission = permission
+	return b
+}
+
+// WithErrorWhenFull sets whether the thin pool of the volume
+// fails the IOs immediately when it is full
+func (b *Builder) WithErrorWhenFull(errorWhenFull string) *Builder {
+	b.volume.Object.Spec.ErrorWhenFull = errorWhenFull
+	return b
+}
+
+// WithIOLimits sets the IO limits of the volume
+func (b *Builder) WithIOLimits(limits *apis.VolumeIOLimits) *Builder {
+	b.volume.Object.Spec.IOLimits = limits
+	return b
+}
+
+// WithSnapName sets the name of the snapshot from which
+// the volume should be restored
+func (b *Builder) WithSnapName(snapName string) *Builder {
+	b.volume.Object.Spec.SnapName = snapName
+	return b
+}
+
+// WithSourceVolume sets the name of the volume from which
+// the volume should be cloned
+func (b *Builder) WithSourceVolume(volName string) *Builder {
+	b.volume.Object.Spec.SourceVolume = volName
+	return b
+}
+
+// WithProgress sets the percentage of the content copied
+// from the source of the volume
+func (b *Builder) WithProgress(percent int32) *Builder {
+	b.volume.Object.Status.Progress = percent
+	return b
+}
+
+// WithCondition sets the health condition of the volume
+func (b *Builder) WithCondition(cond *apis.VolumeCondition) *Builder {
+	b.volume.Object.Status.Condition = cond
+	return b
+}
+
+// WithPublishedPaths sets the target paths where the volume is published
+func (b *Builder) WithPublishedPaths(paths []string) *Builder {
+	b.volume.Object.Status.PublishedPaths = paths
+	return b
+}
+
+// WithRollback sets the progress of the rollback of the volume
+func (b *Builder) WithRollback(rollback *apis.VolumeRollback) *Builder {
+	b.volume.Object.Status.Rollback = rollback
+	return b
+}
+
 // WithVolGroup sets volume group name for creating volume
 func (b *Builder) WithVolGroup(vg string) *Builder {
 	if vg == "" {
@@ -201,6 +280,23 @@ func (b *Builder) WithLabels(labels map[string]string) *Builder {
 	return b
 }
 
+// WithAnnotations merges existing annotations if any
+// with the ones that are provided here
+func (b *Builder) WithAnnotations(annotations map[string]string) *Builder {
+	if len(annotations) == 0 {
+		return b
+	}
+
+	if b.volume.Object.Annotations == nil {
+		b.volume.Object.Annotations = map[string]string{}
+	}
+
+	for key, value := range annotations {
+		b.volume.Object.Annotations[key] = value
+	}
+	return b
+}
+
 // WithFinalizer sets Finalizer name creating the volume
 func (b *Builder) WithFinalizer(finalizer []string) *Builder {
 	b.volume.Object.Finalizers = append(b.volume.Object.Finalizers, finalizer...)
@@ -37,13 +37,17 @@ import (
 	"golang.org/x/sys/unix"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
 	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
 	"github.com/openebs/lvm-localpv/pkg/builder/volbuilder"
 	"github.com/openebs/lvm-localpv/pkg/lvm"
 	"github.com/openebs/lvm-localpv/pkg/mgmt/lvmnode"
+	"github.com/openebs/lvm-localpv/pkg/mgmt/snapgroup"
 	"github.com/openebs/lvm-localpv/pkg/mgmt/snapshot"
 	"github.com/openebs/lvm-localpv/pkg/mgmt/volume"
 
@@ -53,7 +57,12 @@ import (
 // node is the server implementation
 // for CSI NodeServer
 type node struct {
+	csi.UnimplementedNodeServer
+
 	driver *CSIDriver
+
+	// recorder is an event recorder for recording Event resources
+	recorder record.EventRecorder
 }
 
 // NewNode returns a new instance
@@ -88,12 +97,26 @@ func NewNode(d *CSIDriver) csi.NodeServer {
 		}
 	}()
 
+	// start the lvm snapshot group watcher
+	go func() {
+		err := snapgroup.Start(&ControllerMutex, stopCh)
+		if err != nil {
+			klog.Fatalf("Failed to start LVM snapshot group management controller: %s", err.Error())
+		}
+	}()
+
 	if d.config.ListenAddress != "" {
 		exposeMetrics(d.config.ListenAddress, d.config.MetricsPath, d.config.DisableExporterMetrics)
 	}
 
+	kubeClient, err := k8sapi.Clientset().Get()
+	if err != nil {
+		klog.Fatalf("Failed to build k8s clientset: %s", err.Error())
+	}
+
 	return &node{
-		driver: d,
+		driver:   d,
+		recorder: lvm.NewEventRecorder(kubeClient, d.config.DriverName),
 	}
 }
 
@@ -200,7 +223,12 @@ func GetVolAndMountInfo(
 	mountinfo.MountPath = req.GetTargetPath()
 	mountinfo.MountOptions = append(mountinfo.MountOptions, req.GetVolumeCapability().GetMount().GetMountFlags()...)
 
-	if req.GetReadonly() {
+	accessMode := req.GetVolumeCapability().GetAccessMode().GetMode()
+	mountinfo.AccessModes = append(mountinfo.AccessModes, accessMode.String())
+
+	// reader only volumes are always mounted read-only
+	if req.GetReadonly() ||
+		accessMode == csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY {
 		mountinfo.MountOptions = append(mountinfo.MountOptions, "ro")
 	}
 
@@ -230,6 +258,25 @@ func getPodLVInfo(req *csi.NodePublishVolumeRequest) (*lvm.PodLVInfo, error) {
 	return &podLVInfo, nil
 }
 
+// validateSingleWriter refuses to publish a volume having the single node
+// single writer access mode, i.e. a ReadWriteOncePod volume, at a target
+// path while it is published at another one, even if it is shared, as it
+// can only be used by a single pod.
+func validateSingleWriter(vol *apis.LVMVolume, targetPath string,
+	mode csi.VolumeCapability_AccessMode_Mode) error {
+	if mode != csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER {
+		return nil
+	}
+	for _, path := range vol.Status.PublishedPaths {
+		if path != targetPath {
+			return status.Errorf(codes.FailedPrecondition,
+				"volume %s with single node single writer access mode is already published at %s",
+				vol.Name, path)
+		}
+	}
+	return nil
+}
+
 // NodePublishVolume publishes (mounts) the volume
 // at the corresponding node at a given path
 //
@@ -252,6 +299,18 @@ func (ns *node) NodePublishVolume(
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	if err = validateSingleWriter(vol, mountInfo.MountPath,
+		req.GetVolumeCapability().GetAccessMode().GetMode()); err != nil {
+		return nil, err
+	}
+
+	// the volume can not be used while it is being rolled back, and the
+	// rollback is started only once the volume is not open anymore
+	if lvm.IsRollbackRequested(vol) {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"volume %s is being rolled back to snapshot %s", vol.Name, vol.Annotations[lvm.LVMRollbackKey])
+	}
+
 	podLVinfo, err := getPodLVInfo(req)
 	if err != nil {
 		klog.Warningf("PodLVInfo could not be obtained for volume_id: %s, err = %v", req.VolumeId, err)
@@ -269,6 +328,12 @@ func (ns *node) NodePublishVolume(
 		return nil, err
 	}
 
+	if err = lvm.UpdateVolPublishedPath(vol.Name, mountInfo.MountPath, true); err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to record the target path %s of the volume %s: %v",
+			mountInfo.MountPath, vol.Name, err)
+	}
+
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
@@ -309,6 +374,12 @@ func (ns *node) NodeUnpublishVolume(
 	klog.Infof("hostpath: volume %s path: %s has been unmounted.",
 		volumeID, targetPath)
 
+	if err = lvm.UpdateVolPublishedPath(volumeID, targetPath, false); err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to remove the target path %s of the volume %s: %v",
+			targetPath, volumeID, err)
+	}
+
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
@@ -392,6 +463,20 @@ func (ns *node) NodeGetCapabilities(
 					},
 				},
 			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+					},
+				},
+			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
+					},
+				},
+			},
 		},
 	}, nil
 }
@@ -470,6 +555,8 @@ func (ns *node) NodeExpandVolume(
 
 	err = lvm.ResizeLVMVolume(vol, resizeFS)
 	if err != nil {
+		lvm.RecordVolumeEvent(ns.recorder, vol, corev1.EventTypeWarning,
+			lvm.EventReasonResizeFailed, err.Error())
 		return nil, status.Errorf(
 			codes.Internal,
 			"failed to handle NodeExpandVolume Request for %s, {%s}",
@@ -482,6 +569,8 @@ func (ns *node) NodeExpandVolume(
 	if fsType == "btrfs" {
 		err = btrfs.ResizeBTRFS(req.GetVolumePath())
 		if err != nil {
+			lvm.RecordVolumeEvent(ns.recorder, vol, corev1.EventTypeWarning,
+				lvm.EventReasonResizeFailed, err.Error())
 			return nil, status.Errorf(
 				codes.Internal,
 				"failed to handle NodeExpandVolume Request for %s, {%s}",
@@ -536,7 +625,20 @@ func (ns *node) NodeGetVolumeStats(
 		Available: int64(sfs.Ffree),
 	})
 
-	return &csi.NodeGetVolumeStatsResponse{Usage: usage}, nil
+	// the condition of the volume is the one recorded in its status
+	// when the node agent checks the health of the volumes.
+	vol, err := lvm.GetLVMVolume(volID)
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "volume %s not found", volID)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get volume %s: %v", volID, err)
+	}
+
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage:           usage,
+		VolumeCondition: getVolumeCondition(vol),
+	}, nil
 }
 
 func (ns *node) validateNodePublishReq(
@@ -0,0 +1,119 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+func Test_validateSingleWriter(t *testing.T) {
+	const (
+		pod1Path = "/var/lib/kubelet/pods/1/volumes/kubernetes.io~csi/pvc-1/mount"
+		pod2Path = "/var/lib/kubelet/pods/2/volumes/kubernetes.io~csi/pvc-1/mount"
+	)
+	tests := map[string]struct {
+		mode      csi.VolumeCapability_AccessMode_Mode
+		shared    string
+		published []string
+		code      codes.Code
+	}{
+		"single writer not published": {
+			mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+		},
+		"single writer published at the target path": {
+			mode:      csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+			published: []string{pod1Path},
+		},
+		"single writer published at another path": {
+			mode:      csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+			published: []string{pod2Path},
+			code:      codes.FailedPrecondition,
+		},
+		"shared single writer published at another path": {
+			mode:      csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+			shared:    "yes",
+			published: []string{pod2Path},
+			code:      codes.FailedPrecondition,
+		},
+		"multi writer published at another path": {
+			mode:      csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER,
+			shared:    "yes",
+			published: []string{pod2Path},
+		},
+		"single node writer published at another path": {
+			mode:      csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+			published: []string{pod2Path},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vol := &lvmapi.LVMVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+				Spec:       lvmapi.VolumeInfo{Shared: tt.shared},
+				Status:     lvmapi.VolStatus{PublishedPaths: tt.published},
+			}
+			err := validateSingleWriter(vol, pod1Path, tt.mode)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestNodePublishVolumeRollback(t *testing.T) {
+	rolledBack := func(name string, rollback *lvmapi.VolumeRollback) *lvmapi.LVMVolume {
+		return &lvmapi.LVMVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "openebs",
+				Annotations: map[string]string{lvm.LVMRollbackKey: "snapshot-1"},
+			},
+			Spec:   lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg"},
+			Status: lvmapi.VolStatus{State: lvm.LVMStatusReady, Rollback: rollback},
+		}
+	}
+	newTestResourceServer(t, map[string]map[string]interface{}{
+		"lvmvolumes": {
+			"pvc-1": rolledBack("pvc-1", nil),
+			"pvc-2": rolledBack("pvc-2", &lvmapi.VolumeRollback{Snapshot: "snapshot-1", State: lvm.LVMStatusPending}),
+			"pvc-3": rolledBack("pvc-3", &lvmapi.VolumeRollback{Snapshot: "snapshot-1", State: lvm.LVMStatusMerging}),
+		},
+	})
+
+	ns := &node{}
+	for _, volName := range []string{"pvc-1", "pvc-2", "pvc-3"} {
+		_, err := ns.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{
+			VolumeId:   volName,
+			TargetPath: "/var/lib/kubelet/pods/1/volumes/kubernetes.io~csi/" + volName + "/mount",
+			VolumeCapability: &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), volName)
+	}
+}
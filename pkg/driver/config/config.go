@@ -16,6 +16,8 @@ limitations under the License.
 
 package config
 
+import "time"
+
 // Config struct fills the parameters of request or user input
 type Config struct {
 	// DriverName to be registered at CSI
@@ -88,6 +90,40 @@ type Config struct {
 
 	// NodeControllerPollingInterval is the interval, in seconds, between node polling.
 	NodeControllerPollingInterval int
+
+	// LeaderElection enables the leader election among the replicas of
+	// the controller plugin, so that only the leader runs the leak
+	// protection while the others stay on standby.
+	LeaderElection bool
+
+	// LeaderElectionNamespace is the namespace where the leader
+	// election lease is created. It defaults to the LVM namespace.
+	LeaderElectionNamespace string
+
+	// LeaderElectionLeaseDuration is the duration for which the
+	// standby replicas wait before taking over the leadership.
+	LeaderElectionLeaseDuration time.Duration
+
+	// LeaderElectionRenewDeadline is the duration for which the
+	// leader retries renewing the leadership before giving it up.
+	LeaderElectionRenewDeadline time.Duration
+
+	// LeaderElectionRetryPeriod is the duration the replicas wait
+	// between the attempts to acquire or renew the leadership.
+	LeaderElectionRetryPeriod time.Duration
+
+	// WebhookListenAddress is the TCP network address where the admission
+	// webhook validating the StorageClasses and the VolumeSnapshotClasses
+	// of the driver listens. The webhook is disabled if it is empty.
+	WebhookListenAddress string
+
+	// WebhookTLSCertFile is the path of the TLS certificate
+	// served by the admission webhook.
+	WebhookTLSCertFile string
+
+	// WebhookTLSKeyFile is the path of the private key of
+	// the TLS certificate served by the admission webhook.
+	WebhookTLSKeyFile string
 }
 
 // Default returns a new instance of config
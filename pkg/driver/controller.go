@@ -18,15 +18,20 @@ package driver
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	k8sapi "github.com/openebs/lib-csi/pkg/client/k8s"
 	"github.com/openebs/lib-csi/pkg/csipv"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
 	clientset "github.com/openebs/lvm-localpv/pkg/generated/clientset/internalclientset"
@@ -37,14 +42,16 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
 	"github.com/openebs/lib-csi/pkg/common/errors"
-	schd "github.com/openebs/lib-csi/pkg/scheduler"
 
 	analytics "github.com/openebs/google-analytics-4/usage"
 	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
@@ -75,15 +82,34 @@ const (
 // controller is the server implementation
 // for CSI Controller
 type controller struct {
+	csi.UnimplementedControllerServer
+	csi.UnimplementedGroupControllerServer
+
 	driver       *CSIDriver
 	capabilities []*csi.ControllerServiceCapability
 
 	indexedLabel string
 
-	k8sNodeInformer cache.SharedIndexInformer
-	lvmNodeInformer cache.SharedIndexInformer
+	kubeClient kubernetes.Interface
+
+	k8sNodeInformer   cache.SharedIndexInformer
+	lvmNodeInformer   cache.SharedIndexInformer
+	lvmVolumeInformer cache.SharedIndexInformer
+	lvmSnapInformer   cache.SharedIndexInformer
+
+	// pvcLister reads the PVCs overriding the params of their
+	// volumes, or spreading them by their labels.
+	pvcLister corelisters.PersistentVolumeClaimLister
+
+	// statefulSetLister reads the StatefulSets whose volumes
+	// are spread by their volume claim templates.
+	statefulSetLister appslisters.StatefulSetLister
 
-	leakProtection *csipv.LeakProtectionController
+	leakProtection leakProtector
+
+	// recorder is an event recorder for recording Event resources
+	// of the volumes to the Kubernetes API.
+	recorder record.EventRecorder
 }
 
 // NewController returns a new instance
@@ -98,6 +124,12 @@ func NewController(d *CSIDriver) csi.ControllerServer {
 		klog.Fatalf("init controller: %v", err)
 	}
 
+	// the webhook is served by all the replicas, not only by the leader.
+	if cfg := d.config; cfg.WebhookListenAddress != "" {
+		serveWebhook(cfg.WebhookListenAddress, cfg.WebhookTLSCertFile,
+			cfg.WebhookTLSKeyFile, cfg.DriverName)
+	}
+
 	return ctrl
 }
 
@@ -107,6 +139,15 @@ var SupportedVolumeCapabilityAccessModes = []*csi.VolumeCapability_AccessMode{
 	&csi.VolumeCapability_AccessMode{
 		Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
 	},
+	&csi.VolumeCapability_AccessMode{
+		Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+	},
+	&csi.VolumeCapability_AccessMode{
+		Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+	},
+	&csi.VolumeCapability_AccessMode{
+		Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER,
+	},
 }
 
 // sendEventOrIgnore sends anonymous local-pv provision/delete events
@@ -141,12 +182,10 @@ func getRoundedCapacity(size int64) int64 {
 // waitForLVMVolume waits for completion of any processing of lvm volume.
 // It returns the final status of lvm volume along with a boolean denoting
 // whether it should be rescheduled on some other volume group or node.
-// In case volume ends up in failed state and rescheduling is required,
-// func is also deleting the lvm volume resource, so that it can be
-// re provisioned on some other node.
+// The failed lvm volume resource is left as is, so that its failed
+// attempts are kept when it is rescheduled.
 func waitForLVMVolume(ctx context.Context,
 	vol *lvmapi.LVMVolume) (*lvmapi.LVMVolume, bool, error) {
-	var reschedule bool // tracks if rescheduling is required or not.
 	var err error
 	if vol.Status.State == lvm.LVMStatusPending {
 		if vol, err = lvm.WaitForLVMVolumeProcessed(ctx, vol.GetName()); err != nil {
@@ -158,31 +197,48 @@ func waitForLVMVolume(ctx context.Context,
 		return vol, false, nil
 	}
 
-	// Now it must be in failed state if not above. See if we need
-	// to reschedule the lvm volume.
-	var errMsg string
+	// Now it must be in failed state if not above. It can be
+	// rescheduled if the node agent has set the error.
 	if volErr := vol.Status.Error; volErr != nil {
-		errMsg = volErr.Message
-		reschedule = true
-	} else {
-		errMsg = "failed lvmvol must have error set"
+		return vol, true, status.Error(codes.ResourceExhausted, volErr.Message)
 	}
+	return vol, false, status.Error(codes.Aborted, "failed lvmvol must have error set")
+}
 
-	if reschedule {
-		// if rescheduling is required, we can deleted the existing lvm volume object,
-		// so that it can be recreated.
-		if err = lvm.DeleteVolume(vol.GetName()); err != nil {
-			return nil, false, status.Errorf(codes.Aborted,
-				"failed to delete volume %v: %v", vol.GetName(), err)
-		}
-		if err = lvm.WaitForLVMVolumeDestroy(ctx, vol.GetName()); err != nil {
-			return nil, false, status.Errorf(codes.Aborted,
-				"failed to delete volume %v: %v", vol.GetName(), err)
-		}
-		return vol, true, status.Error(codes.ResourceExhausted, errMsg)
+// rescheduleVolume provisions the failed lvm volume again on the node
+// selected by the given schedule func, preferring the nodes where the
+// volume has not failed yet. The failed attempt is recorded in the volume
+// status. The volume is not rescheduled anymore once it has been
+// rescheduled maxReschedules times.
+func rescheduleVolume(ctx context.Context, vol *lvmapi.LVMVolume, maxReschedules int,
+	schedule func(exclude map[string]bool) (string, error)) (*lvmapi.LVMVolume, error) {
+	attempts := vol.Status.Attempts
+	if len(attempts) >= maxReschedules {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"volume %s failed after %d reschedules: %s",
+			vol.Name, len(attempts), vol.Status.Error.Message)
+	}
+
+	exclude := map[string]bool{vol.Spec.OwnerNodeID: true}
+	for _, attempt := range attempts {
+		exclude[attempt.Node] = true
+	}
+	owner, err := schedule(exclude)
+	if err != nil {
+		return nil, err
 	}
 
-	return vol, false, status.Error(codes.Aborted, errMsg)
+	volName := vol.Name
+	klog.Infof("rescheduling the failed volume %s from node %s on node %s (reschedule %d/%d)",
+		volName, vol.Spec.OwnerNodeID, owner, len(attempts)+1, maxReschedules)
+
+	if vol, err = lvm.RescheduleVolume(vol, owner); err != nil {
+		capacityReservations.release(volName)
+		return nil, status.Errorf(codes.Aborted,
+			"failed to reschedule volume %s: %v", volName, err)
+	}
+	vol, _, err = waitForLVMVolume(ctx, vol)
+	return vol, err
 }
 
 func (cs *controller) init() error {
@@ -205,21 +261,32 @@ func (cs *controller) init() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to build k8s clientset")
 	}
+	cs.kubeClient = kubeClient
 
 	openebsClient, err := clientset.NewForConfig(cfg)
 	if err != nil {
 		return errors.Wrap(err, "failed to build openebs clientset")
 	}
 
+	cs.recorder = lvm.NewEventRecorder(kubeClient, cs.driver.config.DriverName)
+
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+	// watch only the resources of this driver instance.
 	openebsInformerfactory := informers.NewSharedInformerFactoryWithOptions(openebsClient,
-		0, informers.WithNamespace(lvm.LvmNamespace))
+		0, informers.WithNamespace(lvm.LvmNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = lvm.DriverLabelSelector()
+		}))
 
 	// set up signals so we handle the first shutdown signal gracefully
 	stopCh := signals.SetupSignalHandler()
 
 	cs.k8sNodeInformer = kubeInformerFactory.Core().V1().Nodes().Informer()
 	cs.lvmNodeInformer = openebsInformerfactory.Local().V1alpha1().LVMNodes().Informer()
+	cs.lvmVolumeInformer = openebsInformerfactory.Local().V1alpha1().LVMVolumes().Informer()
+	cs.lvmSnapInformer = openebsInformerfactory.Local().V1alpha1().LVMSnapshots().Informer()
+	statefulSetInformer := kubeInformerFactory.Apps().V1().StatefulSets()
+	cs.statefulSetLister = statefulSetInformer.Lister()
 
 	if err = cs.lvmNodeInformer.AddIndexers(map[string]cache.IndexFunc{
 		LabelIndexName(cs.indexedLabel): LabelIndexFunc(cs.indexedLabel),
@@ -227,18 +294,45 @@ func (cs *controller) init() error {
 		return errors.Wrapf(err, "failed to add index on label %v", cs.indexedLabel)
 	}
 
+	if err = cs.lvmVolumeInformer.AddIndexers(map[string]cache.IndexFunc{
+		VolumeVolGroupIndex:                   VolumeVolGroupIndexFunc,
+		LabelIndexName(lvm.LVMSpreadGroupKey): LabelIndexFunc(lvm.LVMSpreadGroupKey),
+	}); err != nil {
+		return errors.Wrap(err, "failed to add lvm volume indexes")
+	}
+
+	if _, err = cs.lvmVolumeInformer.AddEventHandler(
+		capacityReservations.volumeEventHandler()); err != nil {
+		return errors.Wrap(err, "failed to add lvm volume event handler")
+	}
+
 	go cs.k8sNodeInformer.Run(stopCh)
 	go cs.lvmNodeInformer.Run(stopCh)
+	go cs.lvmVolumeInformer.Run(stopCh)
+	go cs.lvmSnapInformer.Run(stopCh)
+	go statefulSetInformer.Informer().Run(stopCh)
 
 	// wait for all the caches to be populated.
-	klog.Info("waiting for k8s & lvm node informer caches to be synced")
+	klog.Info("waiting for k8s node, statefulset & lvm node, volume, snapshot informer caches to be synced")
 	cache.WaitForCacheSync(stopCh,
 		cs.k8sNodeInformer.HasSynced,
-		cs.lvmNodeInformer.HasSynced)
-	klog.Info("synced k8s & lvm node informer caches")
+		cs.lvmNodeInformer.HasSynced,
+		cs.lvmVolumeInformer.HasSynced,
+		cs.lvmSnapInformer.HasSynced,
+		statefulSetInformer.Informer().HasSynced)
+	klog.Info("synced k8s node, statefulset & lvm node, volume, snapshot informer caches")
+
+	// schedule the volumes from the informer caches
+	// rather than listing the resources on every request.
+	schedLister = informerLister{
+		k8sNodeIndexer:   cs.k8sNodeInformer.GetIndexer(),
+		lvmNodeIndexer:   cs.lvmNodeInformer.GetIndexer(),
+		lvmVolumeIndexer: cs.lvmVolumeInformer.GetIndexer(),
+	}
 
 	klog.Infof("initializing csi provisioning leak protection controller")
 	pvcInformer := kubeInformerFactory.Core().V1().PersistentVolumeClaims()
+	cs.pvcLister = pvcInformer.Lister()
 	go pvcInformer.Informer().Run(stopCh)
 
 	if lvm.GoogleAnalyticsEnabled == "true" {
@@ -258,8 +352,12 @@ func (cs *controller) init() error {
 	); err != nil {
 		return errors.Wrap(err, "failed to init leak protection controller")
 	}
-	go cs.leakProtection.Run(2, stopCh)
-	return nil
+
+	if !cs.driver.config.LeaderElection {
+		cs.startLeading(stopCh)
+		return nil
+	}
+	return cs.runLeaderElection(kubeClient, stopCh)
 }
 
 // CreateLVMVolume create new lvm volume for csi volume request
@@ -295,38 +393,346 @@ func CreateLVMVolume(ctx context.Context, req *csi.CreateVolumeRequest,
 			if err == nil || !reschedule {
 				return vol, err
 			}
+			return rescheduleVolume(ctx, vol, params.MaxReschedules,
+				func(exclude map[string]bool) (string, error) {
+					return scheduleVolume(req, params, volName, exclude)
+				})
 		}
 	}
 
-	nmap, err := getNodeMap(params.Scheduler, params.VgPattern)
+	owner, err := scheduleVolume(req, params, volName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("scheduling the volume %s/%s on node %s",
+		params.VgPattern.String(), volName, owner)
+
+	builder := volbuilder.NewBuilder()
+	if vgName := selectVolumeGroup(params, owner, volName, req); vgName != "" {
+		klog.Infof("selected the volume group %s for the volume %s", vgName, volName)
+		capacityReservations.assignVolumeGroup(volName, vgName)
+		builder.WithVolGroup(vgName)
+	}
+	if params.ThinProvision == lvm.YES {
+		builder.WithThinPoolThreshold(int32(params.ThinPoolThreshold))
+	}
+
+	volObj, err := builder.
+		WithName(volName).
+		WithCapacity(capacity).
+		WithVgPattern(params.VgPattern.String()).
+		WithOwnerNode(owner).
+		WithVolumeStatus(lvm.LVMStatusPending).
+		WithShared(params.Shared).
+		WithThinProvision(params.ThinProvision).
+		WithDeletionPolicy(params.DeletionPolicy).
+		WithLVName(params.LVName).
+		WithPermission(params.Modify.Permission).
+		WithErrorWhenFull(params.Modify.ErrorWhenFull).
+		WithIOLimits(params.Modify.IOLimits).
+		WithAnnotations(params.pvcAnnotations()).
+		WithLabels(params.spreadLabels()).
+		WithLabels(lvm.DriverLabels()).Build()
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	vol, err = lvm.ProvisionVolume(volObj)
+	if err != nil {
+		capacityReservations.release(volName)
+		return nil, status.Errorf(codes.Internal, "not able to provision the volume %s", err.Error())
+	}
+	vol, _, err = waitForLVMVolume(ctx, vol)
+	return vol, err
+}
+
+// scheduleVolume selects the node where the volume should be provisioned
+// and reserves the capacity of the volume on it until the node agent
+// processes the volume. The selection is serialized, so that the
+// concurrent requests take each other's reservations into account.
+// The nodes in the exclude set are selected only if there is no other
+// node available.
+func scheduleVolume(req *csi.CreateVolumeRequest,
+	params *VolumeParams, volName string, exclude map[string]bool) (string, error) {
+	capacityReservations.schedMu.Lock()
+	defer capacityReservations.schedMu.Unlock()
+
+	size := getRoundedCapacity(req.GetCapacityRange().GetRequiredBytes())
+	nmap, err := params.Scheduler.NodeWeights(params, size)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "get node map failed : %s", err.Error())
+		return "", status.Errorf(codes.Internal, "get node map failed : %s", err.Error())
 	}
 
 	// run the scheduler
-	selected := schd.Scheduler(req, nmap)
+	selected, err := selectNodes(req, nmap)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to list the nodes : %s", err.Error())
+	}
 
 	if len(selected) == 0 {
-		return nil, status.Error(codes.Internal, "scheduler failed, not able to select a node to create the PV")
+		return "", status.Error(codes.Internal, "scheduler failed, not able to select a node to create the PV")
+	}
+
+	if params.ThinProvision == lvm.YES {
+		if selected, err = excludeThinPoolFullNodes(selected, params); err != nil {
+			return "", err
+		}
+	}
+
+	if params.SpreadGroup != "" {
+		if selected, err = spreadNodes(selected, params, volName); err != nil {
+			return "", err
+		}
 	}
 
 	owner := selected[0]
-	klog.Infof("scheduling the volume %s/%s on node %s",
-		params.VgPattern.String(), volName, owner)
+	for _, node := range selected {
+		if !exclude[node] {
+			owner = node
+			break
+		}
+	}
+	capacityReservations.reserve(volName, owner, params.VgPattern.String(), params.SpreadGroup, size)
+	return owner, nil
+}
+
+// excludeThinPoolFullNodes removes the nodes where the thin pools reached
+// the usage threshold from the nodes selected by the scheduler.
+func excludeThinPoolFullNodes(selected []string, params *VolumeParams) ([]string, error) {
+	full, err := getThinPoolFullNodes(params)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get the thin pool usage: %v", err)
+	}
+	nodes := make([]string, 0, len(selected))
+	for _, node := range selected {
+		if !full[node] {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"the thin pools of all the nodes reached the %d%% usage threshold", params.ThinPoolThreshold)
+	}
+	return nodes, nil
+}
+
+// selectVolumeGroup returns the volume group selected by the scheduler
+// for the volume on the owner node, or an empty name if the scheduler does
+// not select the volume groups, in which case the node agent does.
+func selectVolumeGroup(params *VolumeParams, owner, volName string,
+	req *csi.CreateVolumeRequest) string {
+	selector, ok := params.Scheduler.(VolumeGroupSelector)
+	if !ok {
+		return ""
+	}
+	vgName, err := selector.VolumeGroup(owner, volName, params,
+		getRoundedCapacity(req.GetCapacityRange().GetRequiredBytes()))
+	if err != nil {
+		klog.Warningf("failed to select the volume group of the volume %s on node %s: %v",
+			volName, owner, err)
+		return ""
+	}
+	return vgName
+}
+
+// CreateSnapClone creates a new lvm volume having the content of the
+// given snapshot. The volume is created on the node and in the volume
+// group where the snapshot is present.
+func CreateSnapClone(ctx context.Context, req *csi.CreateVolumeRequest,
+	params *VolumeParams, snapshotID string) (*lvmapi.LVMVolume, error) {
+	volName := strings.ToLower(req.GetName())
+	size := getRoundedCapacity(req.GetCapacityRange().GetRequiredBytes())
+	capacity := strconv.FormatInt(size, 10)
+
+	srcVolName, snapName, err := parseSnapshotID(snapshotID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	snap, err := lvm.GetLVMSnapshot(snapName)
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound,
+				"snapshot %s not found", snapshotID)
+		}
+		return nil, status.Errorf(codes.Internal,
+			"failed to get snapshot %s: %v", snapshotID, err)
+	}
+	if snap.Labels[lvm.LVMVolKey] != srcVolName {
+		return nil, status.Errorf(codes.NotFound,
+			"snapshot %s does not belong to volume %s", snapName, srcVolName)
+	}
+	if snap.Status.State != lvm.LVMStatusReady {
+		return nil, status.Errorf(codes.Unavailable,
+			"snapshot %s is not ready to use", snapshotID)
+	}
+
+	srcVol, err := lvm.GetLVMVolume(srcVolName)
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound,
+				"source volume %s of snapshot %s not found", srcVolName, snapshotID)
+		}
+		return nil, status.Errorf(codes.Internal,
+			"failed to get source volume %s: %v", srcVolName, err)
+	}
+
+	// the restored volume can't be smaller than the snapshot restore size,
+	// which is the size of the source volume when the snapshot was taken.
+	// The snapshots taken before it was recorded use the current size.
+	restoreSize := snap.Status.OriginSize
+	if restoreSize == "" {
+		restoreSize = srcVol.Spec.Capacity
+	}
+	snapSize, err := strconv.ParseInt(restoreSize, 10, 64)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to parse restore size of snapshot %s: %v", snapshotID, err)
+	}
+	if size < snapSize {
+		return nil, status.Errorf(codes.OutOfRange,
+			"requested capacity %d is less than the snapshot %s size %d",
+			size, snapshotID, snapSize)
+	}
+
+	klog.Infof("restoring the volume %s from snapshot %s on node %s",
+		volName, snapshotID, snap.Spec.OwnerNodeID)
 
 	volObj, err := volbuilder.NewBuilder().
 		WithName(volName).
 		WithCapacity(capacity).
-		WithVgPattern(params.VgPattern.String()).
-		WithOwnerNode(owner).
+		WithVgPattern(fmt.Sprintf("^%s$", snap.Spec.VolGroup)).
+		WithVolGroup(snap.Spec.VolGroup).
+		WithOwnerNode(snap.Spec.OwnerNodeID).
 		WithVolumeStatus(lvm.LVMStatusPending).
 		WithShared(params.Shared).
-		WithThinProvision(params.ThinProvision).Build()
+		WithThinProvision(srcVol.Spec.ThinProvision).
+		WithDeletionPolicy(params.DeletionPolicy).
+		WithLVName(params.LVName).
+		WithPermission(params.Modify.Permission).
+		WithErrorWhenFull(params.Modify.ErrorWhenFull).
+		WithIOLimits(params.Modify.IOLimits).
+		WithAnnotations(params.pvcAnnotations()).
+		WithLabels(lvm.DriverLabels()).
+		WithSnapName(snapName).Build()
 
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	return provisionVolumeFromSource(ctx, volObj, params.MaxReschedules)
+}
+
+// CreateVolClone creates a new lvm volume having the content of the
+// given source volume. The volume is created on the node and in the
+// volume group where the source volume is present.
+func CreateVolClone(ctx context.Context, req *csi.CreateVolumeRequest,
+	params *VolumeParams, srcVolID string) (*lvmapi.LVMVolume, error) {
+	volName := strings.ToLower(req.GetName())
+	size := getRoundedCapacity(req.GetCapacityRange().GetRequiredBytes())
+	capacity := strconv.FormatInt(size, 10)
+	srcVolName := strings.ToLower(srcVolID)
+
+	srcVol, err := lvm.GetLVMVolume(srcVolName)
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound,
+				"source volume %s not found", srcVolID)
+		}
+		return nil, status.Errorf(codes.Internal,
+			"failed to get source volume %s: %v", srcVolID, err)
+	}
+	if srcVol.DeletionTimestamp != nil || srcVol.Status.State != lvm.LVMStatusReady {
+		return nil, status.Errorf(codes.Unavailable,
+			"source volume %s is not ready to use", srcVolID)
+	}
+
+	// the clone can't be smaller than its source volume.
+	srcSize, err := strconv.ParseInt(srcVol.Spec.Capacity, 10, 64)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to parse capacity of volume %s: %v", srcVolName, err)
+	}
+	if size < srcSize {
+		return nil, status.Errorf(codes.OutOfRange,
+			"requested capacity %d is less than the source volume %s size %d",
+			size, srcVolID, srcSize)
+	}
+
+	klog.Infof("cloning the volume %s from volume %s on node %s",
+		volName, srcVolID, srcVol.Spec.OwnerNodeID)
+
+	volObj, err := volbuilder.NewBuilder().
+		WithName(volName).
+		WithCapacity(capacity).
+		WithVgPattern(fmt.Sprintf("^%s$", srcVol.Spec.VolGroup)).
+		WithVolGroup(srcVol.Spec.VolGroup).
+		WithOwnerNode(srcVol.Spec.OwnerNodeID).
+		WithVolumeStatus(lvm.LVMStatusPending).
+		WithShared(params.Shared).
+		WithThinProvision(srcVol.Spec.ThinProvision).
+		WithDeletionPolicy(params.DeletionPolicy).
+		WithLVName(params.LVName).
+		WithPermission(params.Modify.Permission).
+		WithErrorWhenFull(params.Modify.ErrorWhenFull).
+		WithIOLimits(params.Modify.IOLimits).
+		WithAnnotations(params.pvcAnnotations()).
+		WithLabels(lvm.DriverLabels()).
+		WithSourceVolume(srcVolName).Build()
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return provisionVolumeFromSource(ctx, volObj, params.MaxReschedules)
+}
+
+// provisionVolumeFromSource provisions the lvm volume having a content
+// source, or waits for the already provisioned one if it matches the
+// request. Such a volume can't be rescheduled on another node, as its
+// source is only available on the node of the source, so the failed
+// volume is provisioned again on the same node.
+func provisionVolumeFromSource(ctx context.Context,
+	volObj *lvmapi.LVMVolume, maxReschedules int) (*lvmapi.LVMVolume, error) {
+	volName := volObj.Name
+
+	vol, err := lvm.GetLVMVolume(volName)
+	if err != nil {
+		if !k8serror.IsNotFound(err) {
+			return nil, status.Errorf(codes.Aborted,
+				"failed get lvm volume %v: %v", volName, err.Error())
+		}
+		vol, err = nil, nil
+	}
+
+	if vol != nil {
+		if vol.DeletionTimestamp != nil {
+			if err = lvm.WaitForLVMVolumeDestroy(ctx, volName); err != nil {
+				return nil, err
+			}
+		} else {
+			if vol.Spec.Capacity != volObj.Spec.Capacity ||
+				vol.Spec.SnapName != volObj.Spec.SnapName ||
+				vol.Spec.SourceVolume != volObj.Spec.SourceVolume {
+				return nil, status.Errorf(codes.AlreadyExists,
+					"volume %s already present", volName)
+			}
+			var reschedule bool
+			vol, reschedule, err = waitForLVMVolume(ctx, vol)
+			if err == nil || !reschedule {
+				return vol, err
+			}
+			// the volume can only be created where its source is present,
+			// so it is provisioned again on the same node.
+			return rescheduleVolume(ctx, vol, maxReschedules,
+				func(map[string]bool) (string, error) {
+					return vol.Spec.OwnerNodeID, nil
+				})
+		}
+	}
+
 	vol, err = lvm.ProvisionVolume(volObj)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "not able to provision the volume %s", err.Error())
@@ -345,31 +751,43 @@ func (cs *controller) CreateVolume(
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	params, err := NewVolumeParams(req.GetParameters())
+	params, err := cs.getVolumeParams(ctx, req)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument,
-			"failed to parse csi volume params: %v", err)
+		return nil, err
 	}
 
 	volName := strings.ToLower(req.GetName())
 	size := getRoundedCapacity(req.GetCapacityRange().GetRequiredBytes())
 	contentSource := req.GetVolumeContentSource()
 
+	if params.LVName, err = params.renderLVName(volName); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	modify, err := NewModifyParams(req.GetMutableParameters())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"failed to parse csi volume mutable params: %v", err)
+	}
+	params.Modify = *modify
+
+	// mark volume for leak protection if pvc gets deleted
+	// before the creation of pv.
+	var finishCreateVolume func()
+	if finishCreateVolume, err = cs.leakProtection.BeginCreateVolume(volName,
+		params.PVCNamespace, params.PVCName); err != nil {
+		return nil, err
+	}
+	defer finishCreateVolume()
+
 	var vol *lvmapi.LVMVolume
 	if contentSource != nil && contentSource.GetSnapshot() != nil {
-		return nil, status.Error(codes.Unimplemented, "")
+		snapshotID := contentSource.GetSnapshot().GetSnapshotId()
+		vol, err = CreateSnapClone(ctx, req, params, snapshotID)
 	} else if contentSource != nil && contentSource.GetVolume() != nil {
-		return nil, status.Error(codes.Unimplemented, "")
+		srcVolID := contentSource.GetVolume().GetVolumeId()
+		vol, err = CreateVolClone(ctx, req, params, srcVolID)
 	} else {
-		// mark volume for leak protection if pvc gets deleted
-		// before the creation of pv.
-		var finishCreateVolume func()
-		if finishCreateVolume, err = cs.leakProtection.BeginCreateVolume(volName,
-			params.PVCNamespace, params.PVCName); err != nil {
-			return nil, err
-		}
-		defer finishCreateVolume()
-
 		vol, err = CreateLVMVolume(ctx, req, params)
 	}
 
@@ -392,6 +810,73 @@ func (cs *controller) CreateVolume(
 		Build(), nil
 }
 
+// getVolumeParams parses the storage class parameters of the volume,
+// merged with the overrides set in the annotations of its PVC.
+func (cs *controller) getVolumeParams(ctx context.Context,
+	req *csi.CreateVolumeRequest) (*VolumeParams, error) {
+	params, err := NewVolumeParams(req.GetParameters())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"failed to parse csi volume params: %v", err)
+	}
+	// the pvc name and namespace are passed only when the
+	// external provisioner runs with --extra-create-metadata.
+	if params.PVCName == "" || params.PVCNamespace == "" {
+		return params, nil
+	}
+
+	// the pvc is read only for its annotations overriding the
+	// params, or its labels spreading the volume.
+	overridden := params
+	var pvcLabels map[string]string
+	overrides := allowsPVCOverrides(req.GetParameters())
+	if overrides || params.SpreadBy == SpreadByLabel {
+		pvc, err := cs.getPVC(ctx, params.PVCNamespace, params.PVCName)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get pvc %s/%s: %v",
+				params.PVCNamespace, params.PVCName, err)
+		}
+		pvcLabels = pvc.GetLabels()
+
+		if overrides {
+			m, err := mergePVCOverrides(req.GetParameters(), pvc.GetAnnotations())
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"invalid overrides of pvc %s/%s: %v", params.PVCNamespace, params.PVCName, err)
+			}
+			if overridden, err = NewVolumeParams(m); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"failed to parse csi volume params overridden by pvc %s/%s: %v",
+					params.PVCNamespace, params.PVCName, err)
+			}
+		}
+	}
+	// the PVCs of the StatefulSets are told apart from the others
+	// having a similar name by their volume claim templates.
+	var statefulSets []*appsv1.StatefulSet
+	if overridden.SpreadBy == SpreadByStatefulSet {
+		if statefulSets, err = cs.statefulSetLister.StatefulSets(params.PVCNamespace).
+			List(labels.Everything()); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list the statefulsets of namespace %s: %v",
+				params.PVCNamespace, err)
+		}
+	}
+	overridden.SpreadGroup = overridden.spreadGroup(pvcLabels, statefulSets)
+	return overridden, nil
+}
+
+// getPVC returns the PVC from the informer cache, or from the API
+// server if the PVC has not made it to the cache yet.
+func (cs *controller) getPVC(ctx context.Context,
+	namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	pvc, err := cs.pvcLister.PersistentVolumeClaims(namespace).Get(name)
+	if !k8serror.IsNotFound(err) {
+		return pvc, err
+	}
+	return cs.kubeClient.CoreV1().PersistentVolumeClaims(namespace).
+		Get(ctx, name, metav1.GetOptions{})
+}
+
 // DeleteVolume deletes the specified volume
 func (cs *controller) DeleteVolume(
 	ctx context.Context,
@@ -402,12 +887,83 @@ func (cs *controller) DeleteVolume(
 		return nil, err
 	}
 	volumeID := strings.ToLower(req.GetVolumeId())
+	if err = cs.deleteVolumeSnapshots(ctx, volumeID); err != nil {
+		return nil, err
+	}
 	if err = cs.deleteVolume(ctx, volumeID); err != nil {
 		return nil, err
 	}
 	return csipayload.NewDeleteVolumeResponseBuilder().Build(), nil
 }
 
+// deleteVolumeSnapshots handles the snapshots of the volume being deleted
+// as per the deletion policy of the volume. With the "Block" policy, the
+// deletion of the volume is rejected while it has snapshots, whereas with
+// the "Cascade" policy, the snapshots are deleted before the volume.
+func (cs *controller) deleteVolumeSnapshots(ctx context.Context, volumeID string) error {
+	snapList, err := lvm.GetSnapshotForVolume(volumeID)
+	if err != nil {
+		return status.Errorf(codes.Internal,
+			"failed to handle delete volume request for {%s}, "+
+				"validation failed checking for active snapshots. Error: %s",
+			volumeID, err.Error())
+	}
+	if len(snapList.Items) == 0 {
+		return nil
+	}
+
+	policy := lvm.DeletionPolicyBlock
+	vol, err := lvm.GetLVMVolume(volumeID)
+	if err != nil && !k8serror.IsNotFound(err) {
+		return status.Errorf(codes.Internal,
+			"failed to get volume for {%s}: %s", volumeID, err.Error())
+	}
+	if err == nil && vol.Spec.DeletionPolicy != "" {
+		policy = vol.Spec.DeletionPolicy
+	}
+
+	if policy != lvm.DeletionPolicyCascade {
+		msg := fmt.Sprintf("volume has %d snapshots, delete them first "+
+			"or use the %s deletion policy", len(snapList.Items), lvm.DeletionPolicyCascade)
+		cs.recordVolumeEvent(volumeID, corev1.EventTypeWarning, "DeleteBlocked", msg)
+		return status.Errorf(codes.FailedPrecondition,
+			"failed to handle delete volume request for {%s}: %s", volumeID, msg)
+	}
+
+	for _, snap := range snapList.Items {
+		if snap.GetDeletionTimestamp() == nil {
+			if err = lvm.DeleteSnapshot(snap.Name); err != nil && !k8serror.IsNotFound(err) {
+				cs.recordVolumeEvent(volumeID, corev1.EventTypeWarning, "SnapshotDeleteFailed",
+					fmt.Sprintf("failed to delete snapshot %s: %v", snap.Name, err))
+				return status.Errorf(codes.Internal,
+					"failed to delete snapshot %s of volume {%s}: %s", snap.Name, volumeID, err.Error())
+			}
+		}
+	}
+	for _, snap := range snapList.Items {
+		if err = lvm.WaitForLVMSnapshotDestroy(ctx, snap.Name); err != nil {
+			return err
+		}
+	}
+	cs.recordVolumeEvent(volumeID, corev1.EventTypeNormal, "SnapshotsDeleted",
+		fmt.Sprintf("deleted %d snapshots as per the %s deletion policy",
+			len(snapList.Items), lvm.DeletionPolicyCascade))
+	return nil
+}
+
+// recordVolumeEvent records an event on the persistent volume
+// of the given volume.
+func (cs *controller) recordVolumeEvent(volumeID, eventType, reason, message string) {
+	if cs.recorder == nil {
+		return
+	}
+	cs.recorder.Event(&corev1.ObjectReference{
+		Kind:       "PersistentVolume",
+		APIVersion: "v1",
+		Name:       volumeID,
+	}, eventType, reason, message)
+}
+
 func (cs *controller) deleteVolume(ctx context.Context, volumeID string) error {
 	klog.Infof("received request to delete volume %q", volumeID)
 	vol, err := lvm.GetLVMVolume(volumeID)
@@ -571,6 +1127,8 @@ func (cs *controller) ControllerExpandVolume(
 	}
 
 	if err := lvm.ResizeVolume(vol, updatedSize); err != nil {
+		lvm.RecordVolumeEvent(cs.recorder, vol, corev1.EventTypeWarning,
+			lvm.EventReasonResizeFailed, err.Error())
 		return nil, status.Errorf(
 			codes.Internal,
 			"failed to handle ControllerExpandVolumeRequest for %s, {%s}",
@@ -599,16 +1157,8 @@ func (cs *controller) CreateSnapshot(
 		return nil, err
 	}
 
-	snapTimeStamp := time.Now().Unix()
-	state, err := lvm.GetLVMSnapshotStatus(req.Name)
-
-	if err == nil {
-		return csipayload.NewCreateSnapshotResponseBuilder().
-			WithSourceVolumeID(req.SourceVolumeId).
-			WithSnapshotID(req.SourceVolumeId+"@"+req.Name).
-			WithCreationTime(snapTimeStamp, 0).
-			WithReadyToUse(state == lvm.LVMStatusReady).
-			Build(), nil
+	if snap, err := lvm.GetLVMSnapshot(req.Name); err == nil {
+		return newCreateSnapshotResponse(cs.getCSISnapshot(snap)), nil
 	}
 
 	vol, err := lvm.GetLVMVolume(req.SourceVolumeId)
@@ -638,6 +1188,7 @@ func (cs *controller) CreateSnapshot(
 	snapObj, err := snapbuilder.NewBuilder().
 		WithName(req.Name).
 		WithLabels(labels).
+		WithLabels(lvm.DriverLabels()).
 		WithOwnerNode(vol.Spec.OwnerNodeID).
 		WithVolGroup(vol.Spec.VolGroup).
 		Build()
@@ -666,6 +1217,8 @@ func (cs *controller) CreateSnapshot(
 	snapObj.Status.State = lvm.LVMStatusPending
 
 	if err := lvm.ProvisionSnapshot(snapObj); err != nil {
+		lvm.RecordSnapshotEvent(cs.recorder, snapObj, vol, corev1.EventTypeWarning,
+			lvm.EventReasonSnapshotFailed, err.Error())
 		return nil, status.Errorf(
 			codes.Internal,
 			"failed to handle CreateSnapshotRequest for %s: %s, {%s}",
@@ -674,14 +1227,22 @@ func (cs *controller) CreateSnapshot(
 		)
 	}
 
-	state, _ = lvm.GetLVMSnapshotStatus(req.Name)
+	if snap, err := lvm.GetLVMSnapshot(req.Name); err == nil {
+		snapObj = snap
+	}
+	return newCreateSnapshotResponse(cs.getCSISnapshot(snapObj)), nil
+}
 
+// newCreateSnapshotResponse builds the CreateSnapshot response
+// out of the given csi snapshot.
+func newCreateSnapshotResponse(snap *csi.Snapshot) *csi.CreateSnapshotResponse {
 	return csipayload.NewCreateSnapshotResponseBuilder().
-		WithSourceVolumeID(req.SourceVolumeId).
-		WithSnapshotID(req.SourceVolumeId+"@"+req.Name).
-		WithCreationTime(snapTimeStamp, 0).
-		WithReadyToUse(state == lvm.LVMStatusReady).
-		Build(), nil
+		WithSourceVolumeID(snap.SourceVolumeId).
+		WithSnapshotID(snap.SnapshotId).
+		WithSize(snap.SizeBytes).
+		WithCreationTime(snap.CreationTime.GetSeconds(), int64(snap.CreationTime.GetNanos())).
+		WithReadyToUse(snap.ReadyToUse).
+		Build()
 }
 
 func getSnapSize(params *SnapshotParams, capacity int64) int64 {
@@ -699,6 +1260,16 @@ func getSnapSize(params *SnapshotParams, capacity int64) int64 {
 	return getRoundedCapacity(snapSize)
 }
 
+// parseSnapshotID parses the csi snapshot id which is formed
+// as <volname>@<snapname> and returns the volume and snapshot names.
+func parseSnapshotID(snapshotID string) (string, string, error) {
+	ids := strings.Split(snapshotID, "@")
+	if len(ids) != 2 || ids[0] == "" || ids[1] == "" {
+		return "", "", fmt.Errorf("invalid snapshot id %q, expected <volname>@<snapname>", snapshotID)
+	}
+	return strings.ToLower(ids[0]), strings.ToLower(ids[1]), nil
+}
+
 // DeleteSnapshot deletes given snapshot
 //
 // This implements csi.ControllerServer
@@ -722,7 +1293,8 @@ func (cs *controller) DeleteSnapshot(
 		)
 	}
 
-	if err := lvm.DeleteSnapshot(snapshotID[1]); err != nil {
+	// the snapshot may have been deleted along with its volume
+	if err := lvm.DeleteSnapshot(snapshotID[1]); err != nil && !k8serror.IsNotFound(err) {
 		return nil, status.Errorf(
 			codes.Internal,
 			"failed to handle DeleteSnapshot for %s, {%s}",
@@ -743,7 +1315,92 @@ func (cs *controller) ListSnapshots(
 	req *csi.ListSnapshotsRequest,
 ) (*csi.ListSnapshotsResponse, error) {
 
-	return nil, status.Error(codes.Unimplemented, "")
+	if err := cs.validateRequest(
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+	); err != nil {
+		return nil, err
+	}
+
+	var snapName string
+	srcVolName := strings.ToLower(req.GetSourceVolumeId())
+	if req.GetSnapshotId() != "" {
+		volName, name, err := parseSnapshotID(req.GetSnapshotId())
+		if err != nil {
+			// snapshot having an invalid id can't exist.
+			return csipayload.NewListSnapshotsResponseBuilder().Build(), nil
+		}
+		if srcVolName != "" && srcVolName != volName {
+			return csipayload.NewListSnapshotsResponseBuilder().Build(), nil
+		}
+		srcVolName, snapName = volName, name
+	}
+
+	var snaps []*lvmapi.LVMSnapshot
+	for _, obj := range cs.lvmSnapInformer.GetIndexer().List() {
+		snap, ok := obj.(*lvmapi.LVMSnapshot)
+		if !ok {
+			continue
+		}
+		if snapName != "" && snap.Name != snapName {
+			continue
+		}
+		if srcVolName != "" && snap.Labels[lvm.LVMVolKey] != srcVolName {
+			continue
+		}
+		snaps = append(snaps, snap)
+	}
+	// order of the listing must be stable across the pages.
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].Name < snaps[j].Name
+	})
+
+	start, end, nextToken, err := paginate(len(snaps),
+		req.GetMaxEntries(), req.GetStartingToken())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := csipayload.NewListSnapshotsResponseBuilder()
+	for _, snap := range snaps[start:end] {
+		resp.WithEntry(cs.getCSISnapshot(snap))
+	}
+	return resp.WithNextToken(nextToken).Build(), nil
+}
+
+// getCSISnapshot returns the csi snapshot of the given lvm snapshot. The
+// creation time and the size of the snapshot are the ones recorded by the
+// node agent when the snapshot has been created. Until then, the creation
+// time of the LVMSnapshot resource and the current capacity of the source
+// volume are reported.
+func (cs *controller) getCSISnapshot(snap *lvmapi.LVMSnapshot) *csi.Snapshot {
+	srcVolName := snap.Labels[lvm.LVMVolKey]
+
+	size, _ := strconv.ParseInt(snap.Status.OriginSize, 10, 64)
+	if size == 0 {
+		obj, exists, err := cs.lvmVolumeInformer.GetIndexer().
+			GetByKey(lvmCacheKey(srcVolName))
+		if err != nil {
+			klog.Warningf("failed to get source volume %s of snapshot %s: %v",
+				srcVolName, snap.Name, err)
+		} else if exists {
+			if vol, ok := obj.(*lvmapi.LVMVolume); ok {
+				size, _ = strconv.ParseInt(vol.Spec.Capacity, 10, 64)
+			}
+		}
+	}
+
+	creationTime := snap.CreationTimestamp.Time
+	if snap.Status.CreationTime != nil {
+		creationTime = snap.Status.CreationTime.Time
+	}
+
+	return &csi.Snapshot{
+		SnapshotId:     srcVolName + "@" + snap.Name,
+		SourceVolumeId: srcVolName,
+		SizeBytes:      size,
+		CreationTime:   timestamppb.New(creationTime),
+		ReadyToUse:     snap.Status.State == lvm.LVMStatusReady,
+	}
 }
 
 // ControllerUnpublishVolume removes a previously
@@ -798,7 +1455,7 @@ func (cs *controller) GetCapacity(
 
 	var availableCapacity int64
 	for _, nodeName := range nodeNames {
-		v, exists, err := lvmNodesCache.GetByKey(lvm.LvmNamespace + "/" + nodeName)
+		v, exists, err := lvmNodesCache.GetByKey(lvmCacheKey(lvm.GetLVMNodeName(nodeName)))
 		if err != nil {
 			klog.Warning("unexpected error after querying the lvmNode informer cache")
 			continue
@@ -807,26 +1464,86 @@ func (cs *controller) GetCapacity(
 			continue
 		}
 		lvmNode := v.(*lvmapi.LVMNode)
+		// capacity reserved for the volumes not yet provisioned on the node
+		reserved := capacityReservations.volumeGroupReserved(lvmNode, params.VgPattern, nil)
 		// rather than summing all free capacity, we are calculating maximum
 		// lv size that gets fit in given vg.
 		// See https://github.com/kubernetes/enhancements/tree/master/keps/sig-storage/1472-storage-capacity-tracking#available-capacity-vs-maximum-volume-size &
 		// https://github.com/container-storage-interface/spec/issues/432 for more details
-		for _, vg := range lvmNode.VolumeGroups {
+		for i := range lvmNode.VolumeGroups {
+			vg := &lvmNode.VolumeGroups[i]
 			if !params.VgPattern.MatchString(vg.Name) {
 				continue
 			}
-			freeCapacity := vg.Free.Value()
+			freeCapacity := getVGCapacity(vg, params) - reserved[vg.Name]
 			if availableCapacity < freeCapacity {
 				availableCapacity = freeCapacity
 			}
 		}
 	}
 
+	// the volumes can not span across the volume groups, so the available
+	// capacity is also the size of the largest volume which can be created
 	return &csi.GetCapacityResponse{
 		AvailableCapacity: availableCapacity,
+		MaximumVolumeSize: wrapperspb.Int64(availableCapacity),
+		MinimumVolumeSize: wrapperspb.Int64(Mi),
 	}, nil
 }
 
+// getVGCapacity returns the size of the largest volume which can be
+// provisioned in the given volume group. For thin provisioned volumes,
+// the size the thin pool can grow to, i.e. its size plus the free space of
+// the volume group, is overcommitted by the overprovisioning ratio, less
+// the size already allocated from the pool. If the thin pool does not exist
+// yet, it gets created out of the free space of the volume group. No
+// capacity is left in the thin pools whose usage reached the threshold.
+func getVGCapacity(vg *lvmapi.VolumeGroup, params *VolumeParams) int64 {
+	if params.ThinProvision != lvm.YES {
+		return vg.Free.Value()
+	}
+
+	pool := lvm.GetThinPool(vg)
+	if pool == nil {
+		return int64(float64(vg.Free.Value()) * params.OverProvisioningRatio)
+	}
+	if thinPoolFull(pool, params) {
+		return 0
+	}
+	// the thin pool is created with the size of its first volume, and
+	// extended out of the free space of the volume group afterwards
+	poolSize := pool.Size.Value() + vg.Free.Value()
+	capacity := int64(float64(poolSize)*params.OverProvisioningRatio) -
+		pool.VirtualSize.Value()
+	if capacity < 0 {
+		return 0
+	}
+	return capacity
+}
+
+// getVGFreeSpace returns the free space of the volume group considered
+// by the space weighted scheduler. For thin provisioned volumes, it is
+// the capacity left in the thin pool scaled down by its data or metadata
+// usage, so that the pools filling up are not preferred even when they
+// are largely overprovisioned.
+func getVGFreeSpace(vg *lvmapi.VolumeGroup, params *VolumeParams) int64 {
+	capacity := getVGCapacity(vg, params)
+	if params.ThinProvision != lvm.YES {
+		return capacity
+	}
+	pool := lvm.GetThinPool(vg)
+	if pool == nil {
+		return capacity
+	}
+	return int64(float64(capacity) * (100 - lvm.ThinPoolUsage(pool)) / 100)
+}
+
+// thinPoolFull returns true if the data or metadata usage
+// of the thin pool reached the threshold of the volume.
+func thinPoolFull(pool *lvmapi.ThinPool, params *VolumeParams) bool {
+	return lvm.ThinPoolUsage(pool) >= float64(params.ThinPoolThreshold)
+}
+
 func (cs *controller) filterNodesByTopology(segments map[string]string) ([]string, error) {
 	nodesCache := cs.k8sNodeInformer.GetIndexer()
 	if len(segments) == 0 {
@@ -876,42 +1593,251 @@ func (cs *controller) ListVolumes(
 	req *csi.ListVolumesRequest,
 ) (*csi.ListVolumesResponse, error) {
 
-	return nil, status.Error(codes.Unimplemented, "")
+	if err := cs.validateRequest(
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+	); err != nil {
+		return nil, err
+	}
+
+	// only the volumes which have been provisioned are listed, pending
+	// and failed volumes are not yet known to the CO.
+	var vols []*lvmapi.LVMVolume
+	for _, obj := range cs.lvmVolumeInformer.GetIndexer().List() {
+		vol, ok := obj.(*lvmapi.LVMVolume)
+		if !ok || vol.Status.State != lvm.LVMStatusReady {
+			continue
+		}
+		vols = append(vols, vol)
+	}
+	// order of the listing must be stable across the pages.
+	sort.Slice(vols, func(i, j int) bool {
+		return vols[i].Name < vols[j].Name
+	})
+
+	start, end, nextToken, err := paginate(len(vols),
+		req.GetMaxEntries(), req.GetStartingToken())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := csipayload.NewListVolumesResponseBuilder()
+	for _, vol := range vols[start:end] {
+		csiVol, err := getCSIVolume(vol)
+		if err != nil {
+			return nil, err
+		}
+		resp.WithEntry(csiVol, getPublishedNodes(vol), getVolumeCondition(vol))
+	}
+
+	return resp.WithNextToken(nextToken).Build(), nil
 }
 
-func (cs *controller) validateDeleteVolumeReq(req *csi.DeleteVolumeRequest) error {
+// ControllerModifyVolume modifies the mutable properties of the volume
+// set in its volume attributes class. The properties are recorded in the
+// LVMVolume and applied by the node agent on the node of the volume.
+//
+// This implements csi.ControllerServer
+func (cs *controller) ControllerModifyVolume(
+	ctx context.Context,
+	req *csi.ControllerModifyVolumeRequest,
+) (*csi.ControllerModifyVolumeResponse, error) {
+
+	if err := cs.validateRequest(
+		csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
+	); err != nil {
+		return nil, err
+	}
+
 	volumeID := strings.ToLower(req.GetVolumeId())
 	if volumeID == "" {
-		return status.Error(
-			codes.InvalidArgument,
-			"failed to handle delete volume request: missing volume id",
-		)
+		return nil, status.Error(codes.InvalidArgument, "ControllerModifyVolume: missing volume id")
 	}
 
-	// volume should not be deleted if there are active snapshots present for the volume
-	snapList, err := lvm.GetSnapshotForVolume(volumeID)
+	params, err := NewModifyParams(req.GetMutableParameters())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"failed to parse csi volume mutable params: %v", err)
+	}
 
+	vol, err := lvm.GetLVMVolume(volumeID)
 	if err != nil {
-		return status.Errorf(
-			codes.NotFound,
-			"failed to handle delete volume request for {%s}, "+
-				"validation failed checking for active snapshots. Error: %s",
-			req.VolumeId,
-			err.Error(),
-		)
+		if k8serror.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "volume %s not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal,
+			"failed to get volume %s: %v", volumeID, err)
+	}
+	if params.ErrorWhenFull != "" && vol.Spec.ThinProvision != lvm.YES {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"errorWhenFull can not be set on the volume %s which is not thin provisioned", volumeID)
+	}
+	if err = cs.checkErrorWhenFull(vol, params.ErrorWhenFull); err != nil {
+		return nil, err
 	}
 
-	// delete is not supported if there are any snapshots present for the volume
-	if len(snapList.Items) != 0 {
-		return status.Errorf(
-			codes.Internal,
-			"failed to handle delete volume request for {%s} with %d active snapshots",
-			req.VolumeId,
-			len(snapList.Items),
+	if err = lvm.ModifyVolume(vol, params.Permission,
+		params.ErrorWhenFull, params.IOLimits); err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to modify volume %s: %v", volumeID, err)
+	}
+	klog.Infof("modified the volume %s: %+v", volumeID, req.GetMutableParameters())
+
+	return &csi.ControllerModifyVolumeResponse{}, nil
+}
+
+// checkErrorWhenFull fails if the errorWhenFull differs from the one of
+// another volume sharing the thin pool of the volume, as it applies to
+// the whole thin pool.
+func (cs *controller) checkErrorWhenFull(vol *lvmapi.LVMVolume, errorWhenFull string) error {
+	if errorWhenFull == "" {
+		return nil
+	}
+	objs, err := cs.lvmVolumeInformer.GetIndexer().ByIndex(VolumeVolGroupIndex, vol.Spec.VolGroup)
+	if err != nil {
+		return status.Errorf(codes.Internal,
+			"failed to list the volumes of volume group %s: %v", vol.Spec.VolGroup, err)
+	}
+	vols := make([]*lvmapi.LVMVolume, 0, len(objs))
+	for _, obj := range objs {
+		if other, ok := obj.(*lvmapi.LVMVolume); ok {
+			vols = append(vols, other)
+		}
+	}
+	if other := lvm.ErrorWhenFullConflict(vol, errorWhenFull, vols); other != "" {
+		return status.Errorf(codes.FailedPrecondition,
+			"errorWhenFull %s of the volume %s differs from the one of the volume %s sharing its thin pool",
+			errorWhenFull, vol.Name, other)
+	}
+	return nil
+}
+
+// ControllerGetVolume returns the volume along with the node where
+// it is published and its condition as observed by the node agent
+//
+// This implements csi.ControllerServer
+func (cs *controller) ControllerGetVolume(
+	ctx context.Context,
+	req *csi.ControllerGetVolumeRequest,
+) (*csi.ControllerGetVolumeResponse, error) {
+
+	if err := cs.validateRequest(
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+	); err != nil {
+		return nil, err
+	}
+
+	volumeID := strings.ToLower(req.GetVolumeId())
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "ControllerGetVolume: missing volume id")
+	}
+
+	obj, exists, err := cs.lvmVolumeInformer.GetIndexer().GetByKey(lvmCacheKey(volumeID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to get volume %s: %v", volumeID, err)
+	}
+	vol, ok := obj.(*lvmapi.LVMVolume)
+	if !exists || !ok || vol.Status.State != lvm.LVMStatusReady {
+		return nil, status.Errorf(codes.NotFound, "volume %s not found", volumeID)
+	}
+
+	csiVol, err := getCSIVolume(vol)
+	if err != nil {
+		return nil, err
+	}
+	return &csi.ControllerGetVolumeResponse{
+		Volume: csiVol,
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			PublishedNodeIds: getPublishedNodes(vol),
+			VolumeCondition:  getVolumeCondition(vol),
+		},
+	}, nil
+}
+
+// getCSIVolume returns the csi volume for the given lvm volume.
+func getCSIVolume(vol *lvmapi.LVMVolume) (*csi.Volume, error) {
+	capacity, err := strconv.ParseInt(vol.Spec.Capacity, 10, 64)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to parse capacity of volume %s: %v", vol.Name, err)
+	}
+	return &csi.Volume{
+		VolumeId:      vol.Name,
+		CapacityBytes: capacity,
+		VolumeContext: map[string]string{
+			lvm.VolGroupKey:       vol.Spec.VolGroup,
+			lvm.OpenEBSCasTypeKey: lvm.LVMCasTypeName,
+		},
+		AccessibleTopology: []*csi.Topology{{
+			Segments: map[string]string{lvm.LVMTopologyKey: vol.Spec.OwnerNodeID},
+		}},
+	}, nil
+}
+
+// getPublishedNodes returns the ids of the nodes where the volume is
+// published. lvm volumes are local to the node, so a volume can only be
+// published on the node where it has been provisioned, once the node
+// agent has recorded a target path of the volume.
+func getPublishedNodes(vol *lvmapi.LVMVolume) []string {
+	if len(vol.Status.PublishedPaths) == 0 {
+		return nil
+	}
+	return []string{vol.Spec.OwnerNodeID}
+}
+
+// getVolumeCondition returns the condition of the volume recorded by the
+// node agent. The volume is reported as normal until it has been checked.
+func getVolumeCondition(vol *lvmapi.LVMVolume) *csi.VolumeCondition {
+	if vol.Status.Condition == nil {
+		return &csi.VolumeCondition{}
+	}
+	return &csi.VolumeCondition{
+		Abnormal: vol.Status.Condition.Abnormal,
+		Message:  vol.Status.Condition.Message,
+	}
+}
+
+// paginate returns the range [start, end) of the entries to be listed
+// in a page along with the token of the next page. The token is the
+// index of the first entry of the page in the ordered listing.
+func paginate(total int, maxEntries int32, startingToken string) (int, int, string, error) {
+	if maxEntries < 0 {
+		return 0, 0, "", status.Errorf(codes.InvalidArgument,
+			"max_entries %d can not be negative", maxEntries)
+	}
+
+	start := 0
+	if startingToken != "" {
+		var err error
+		start, err = strconv.Atoi(startingToken)
+		if err != nil || start < 0 || start > total {
+			return 0, 0, "", status.Errorf(codes.Aborted,
+				"invalid starting_token %q", startingToken)
+		}
+	}
+
+	end := total
+	if maxEntries > 0 && start+int(maxEntries) < total {
+		end = start + int(maxEntries)
+	}
+
+	var nextToken string
+	if end < total {
+		nextToken = strconv.Itoa(end)
+	}
+	return start, end, nextToken, nil
+}
+
+func (cs *controller) validateDeleteVolumeReq(req *csi.DeleteVolumeRequest) error {
+	volumeID := strings.ToLower(req.GetVolumeId())
+	if volumeID == "" {
+		return status.Error(
+			codes.InvalidArgument,
+			"failed to handle delete volume request: missing volume id",
 		)
 	}
 
-	err = cs.validateRequest(
+	err := cs.validateRequest(
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 	)
 	if err != nil {
@@ -958,6 +1884,14 @@ func newControllerCapabilities() []*csi.ControllerServiceCapability {
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
 		csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+		csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+		csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
+		csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
+		csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
 	} {
 		capabilities = append(capabilities, fromType(cap))
 	}
@@ -1013,7 +1947,8 @@ func (cs *controller) validateVolumeCreateReq(req *csi.CreateVolumeRequest) erro
 		// VolumeCapabilities will contain volume mode
 		if mode := volCap.GetAccessMode(); mode != nil {
 			inputMode := mode.GetMode()
-			// At the moment we only support SINGLE_NODE_WRITER i.e Read-Write-Once
+			// volumes are local to a node, so only the single node access
+			// modes are supported.
 			var isModeSupported bool
 			for _, supporteVolCapability := range SupportedVolumeCapabilityAccessModes {
 				if inputMode == supporteVolCapability.Mode {
@@ -1024,7 +1959,7 @@ func (cs *controller) validateVolumeCreateReq(req *csi.CreateVolumeRequest) erro
 
 			if !isModeSupported {
 				return status.Errorf(codes.InvalidArgument,
-					"only ReadwriteOnce access mode is supported",
+					"only single node access modes are supported",
 				)
 			}
 		}
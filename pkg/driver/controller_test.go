@@ -17,9 +17,23 @@ limitations under the License.
 package driver
 
 import (
+	"context"
 	"testing"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
 )
 
 func TestRoundOff(t *testing.T) {
@@ -96,3 +110,638 @@ func Test_getSnapSize(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseSnapshotID(t *testing.T) {
+	tests := map[string]struct {
+		snapshotID string
+		volName    string
+		snapName   string
+		wantErr    bool
+	}{
+		"valid snapshot id":       {snapshotID: "pvc-1@snapshot-1", volName: "pvc-1", snapName: "snapshot-1"},
+		"missing separator":       {snapshotID: "pvc-1", wantErr: true},
+		"missing volume name":     {snapshotID: "@snapshot-1", wantErr: true},
+		"missing snapshot name":   {snapshotID: "pvc-1@", wantErr: true},
+		"more than one separator": {snapshotID: "pvc-1@snap@1", wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			volName, snapName, err := parseSnapshotID(test.snapshotID)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.volName, volName)
+			assert.Equal(t, test.snapName, snapName)
+		})
+	}
+}
+
+func Test_paginate(t *testing.T) {
+	tests := map[string]struct {
+		total         int
+		maxEntries    int32
+		startingToken string
+		start, end    int
+		nextToken     string
+		wantErr       bool
+	}{
+		"all entries":           {total: 5, start: 0, end: 5},
+		"first page":            {total: 5, maxEntries: 2, start: 0, end: 2, nextToken: "2"},
+		"middle page":           {total: 5, maxEntries: 2, startingToken: "2", start: 2, end: 4, nextToken: "4"},
+		"last page":             {total: 5, maxEntries: 2, startingToken: "4", start: 4, end: 5},
+		"max entries exceeding": {total: 5, maxEntries: 10, startingToken: "1", start: 1, end: 5},
+		"no entries":            {total: 0, maxEntries: 2, start: 0, end: 0},
+		"token at the end":      {total: 5, startingToken: "5", start: 5, end: 5},
+		"token out of range":    {total: 5, startingToken: "6", wantErr: true},
+		"invalid token":         {total: 5, startingToken: "abc", wantErr: true},
+		"negative max entries":  {total: 5, maxEntries: -1, wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			start, end, nextToken, err := paginate(test.total, test.maxEntries, test.startingToken)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.start, start)
+			assert.Equal(t, test.end, end)
+			assert.Equal(t, test.nextToken, nextToken)
+		})
+	}
+}
+
+// newTestInformer returns an informer, which is not run,
+// whose cache holds the given objects.
+func newTestInformer(t *testing.T, objType runtime.Object, objs ...runtime.Object) cache.SharedIndexInformer {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, objType, 0, cache.Indexers{})
+	for _, obj := range objs {
+		assert.NoError(t, informer.GetIndexer().Add(obj))
+	}
+	return informer
+}
+
+func TestListVolumes(t *testing.T) {
+	volume := func(name, state string, paths ...string) runtime.Object {
+		return &lvmapi.LVMVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg", Capacity: "1073741824"},
+			Status:     lvmapi.VolStatus{State: state, PublishedPaths: paths},
+		}
+	}
+	cs := &controller{
+		capabilities: newControllerCapabilities(),
+		lvmVolumeInformer: newTestInformer(t, &lvmapi.LVMVolume{},
+			volume("pvc-3", lvm.LVMStatusReady),
+			volume("pvc-1", lvm.LVMStatusReady, "/var/lib/kubelet/pods/1/volumes/mount"),
+			volume("pvc-2", lvm.LVMStatusPending),
+			volume("pvc-4", lvm.LVMStatusReady),
+		),
+	}
+
+	tests := map[string]struct {
+		req       *csi.ListVolumesRequest
+		volumes   []string
+		published [][]string
+		nextToken string
+		code      codes.Code
+	}{
+		"all": {
+			req:       &csi.ListVolumesRequest{},
+			volumes:   []string{"pvc-1", "pvc-3", "pvc-4"},
+			published: [][]string{{"node-1"}, nil, nil},
+		},
+		"first page": {
+			req:       &csi.ListVolumesRequest{MaxEntries: 2},
+			volumes:   []string{"pvc-1", "pvc-3"},
+			published: [][]string{{"node-1"}, nil},
+			nextToken: "2",
+		},
+		"last page": {
+			req:       &csi.ListVolumesRequest{MaxEntries: 2, StartingToken: "2"},
+			volumes:   []string{"pvc-4"},
+			published: [][]string{nil},
+		},
+		"invalid token": {
+			req:  &csi.ListVolumesRequest{StartingToken: "4"},
+			code: codes.Aborted,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := cs.ListVolumes(context.Background(), tt.req)
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err))
+				return
+			}
+			assert.NoError(t, err)
+			var volumes []string
+			var published [][]string
+			for _, entry := range resp.GetEntries() {
+				volumes = append(volumes, entry.GetVolume().GetVolumeId())
+				published = append(published, entry.GetStatus().GetPublishedNodeIds())
+			}
+			assert.Equal(t, tt.volumes, volumes)
+			assert.Equal(t, tt.published, published)
+			assert.Equal(t, tt.nextToken, resp.GetNextToken())
+		})
+	}
+}
+
+func TestControllerGetVolume(t *testing.T) {
+	cs := &controller{
+		capabilities: newControllerCapabilities(),
+		lvmVolumeInformer: newTestInformer(t, &lvmapi.LVMVolume{},
+			&lvmapi.LVMVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+				Spec:       lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg", Capacity: "1073741824"},
+				Status: lvmapi.VolStatus{
+					State:          lvm.LVMStatusReady,
+					PublishedPaths: []string{"/var/lib/kubelet/pods/1/volumes/mount"},
+					Condition:      &lvmapi.VolumeCondition{Abnormal: true, Message: "thin pool lvmvg_thinpool data is full"},
+				},
+			},
+			&lvmapi.LVMVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc-2"},
+				Spec:       lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg", Capacity: "1073741824"},
+				Status:     lvmapi.VolStatus{State: lvm.LVMStatusReady},
+			},
+			&lvmapi.LVMVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc-3"},
+				Spec:       lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg", Capacity: "1073741824"},
+				Status:     lvmapi.VolStatus{State: lvm.LVMStatusPending},
+			},
+		),
+	}
+
+	tests := map[string]struct {
+		volumeID  string
+		published []string
+		condition *csi.VolumeCondition
+		code      codes.Code
+	}{
+		"abnormal": {
+			volumeID:  "pvc-1",
+			published: []string{"node-1"},
+			condition: &csi.VolumeCondition{Abnormal: true, Message: "thin pool lvmvg_thinpool data is full"},
+		},
+		"not checked": {
+			volumeID:  "pvc-2",
+			condition: &csi.VolumeCondition{},
+		},
+		"pending":    {volumeID: "pvc-3", code: codes.NotFound},
+		"unknown":    {volumeID: "pvc-4", code: codes.NotFound},
+		"missing id": {code: codes.InvalidArgument},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := cs.ControllerGetVolume(context.Background(),
+				&csi.ControllerGetVolumeRequest{VolumeId: tt.volumeID})
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.volumeID, resp.GetVolume().GetVolumeId())
+			assert.Equal(t, int64(1073741824), resp.GetVolume().GetCapacityBytes())
+			assert.Equal(t, tt.published, resp.GetStatus().GetPublishedNodeIds())
+			assert.Equal(t, tt.condition.GetAbnormal(), resp.GetStatus().GetVolumeCondition().GetAbnormal())
+			assert.Equal(t, tt.condition.GetMessage(), resp.GetStatus().GetVolumeCondition().GetMessage())
+		})
+	}
+}
+
+func TestControllerModifyVolume(t *testing.T) {
+	cs := &controller{capabilities: newControllerCapabilities()}
+	tests := map[string]*csi.ControllerModifyVolumeRequest{
+		"missing id": {MutableParameters: map[string]string{"permission": "r"}},
+		"immutable":  {VolumeId: "pvc-1", MutableParameters: map[string]string{"volgroup": "lvmvg"}},
+		"invalid":    {VolumeId: "pvc-1", MutableParameters: map[string]string{"writeIOPS": "fast"}},
+	}
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := cs.ControllerModifyVolume(context.Background(), req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+
+	// the errorWhenFull of the thin pool shared with pvc-2 can not be changed
+	thinVolume := func(name, errorWhenFull string) *lvmapi.LVMVolume {
+		return &lvmapi.LVMVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openebs"},
+			Spec: lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg",
+				ThinProvision: "yes", ErrorWhenFull: errorWhenFull},
+		}
+	}
+	newTestResourceServer(t, map[string]map[string]interface{}{
+		"lvmvolumes": {"pvc-1": thinVolume("pvc-1", "")},
+	})
+	cs.lvmVolumeInformer = cache.NewSharedIndexInformer(&cache.ListWatch{}, &lvmapi.LVMVolume{}, 0,
+		cache.Indexers{VolumeVolGroupIndex: VolumeVolGroupIndexFunc})
+	for _, vol := range []*lvmapi.LVMVolume{thinVolume("pvc-1", ""), thinVolume("pvc-2", "no")} {
+		assert.NoError(t, cs.lvmVolumeInformer.GetIndexer().Add(vol))
+	}
+	_, err := cs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+		VolumeId:          "pvc-1",
+		MutableParameters: map[string]string{"errorWhenFull": "yes"},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), err)
+}
+
+func TestGetVolumeParamsPVC(t *testing.T) {
+	cached := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name: "cached", Namespace: "db",
+		Labels:      map[string]string{"app": "mysql"},
+		Annotations: map[string]string{PVCOverridePrefix + "thinprovision": "yes"},
+	}}
+	created := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name: "created", Namespace: "db",
+		Annotations: map[string]string{PVCOverridePrefix + "thinprovision": "yes"},
+	}}
+	tests := map[string]struct {
+		pvcName   string
+		params    map[string]string
+		wantThin  string
+		wantGroup bool
+		wantGets  int
+		code      codes.Code
+	}{
+		"no overrides": {
+			pvcName:  "missing",
+			wantThin: "no",
+		},
+		"cached overrides": {
+			pvcName:  "cached",
+			params:   map[string]string{"allowedOverrides": "thinProvision"},
+			wantThin: "yes",
+		},
+		"created overrides": {
+			pvcName:  "created",
+			params:   map[string]string{"allowedOverrides": "thinProvision"},
+			wantThin: "yes",
+			wantGets: 1,
+		},
+		"spread by label": {
+			pvcName:   "cached",
+			params:    map[string]string{"spreadBy": SpreadByLabel, "spreadLabel": "app"},
+			wantThin:  "no",
+			wantGroup: true,
+		},
+		"missing pvc": {
+			pvcName:  "missing",
+			params:   map[string]string{"allowedOverrides": "thinProvision"},
+			wantGets: 1,
+			code:     codes.Internal,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(created)
+			cs := &controller{
+				kubeClient: kubeClient,
+				pvcLister: corelisters.NewPersistentVolumeClaimLister(
+					newTestInformer(t, &corev1.PersistentVolumeClaim{}, cached).GetIndexer()),
+			}
+			params := map[string]string{
+				"volgroup":                         "lvmvg",
+				"csi.storage.k8s.io/pvc/name":      tt.pvcName,
+				"csi.storage.k8s.io/pvc/namespace": "db",
+			}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+			got, err := cs.getVolumeParams(context.Background(),
+				&csi.CreateVolumeRequest{Parameters: params})
+			assert.Equal(t, tt.code, status.Code(err), err)
+			assert.Len(t, kubeClient.Actions(), tt.wantGets)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.wantThin, got.ThinProvision)
+			assert.Equal(t, tt.wantGroup, got.SpreadGroup != "")
+		})
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	snapshot := func(volName, name string) runtime.Object {
+		return &lvmapi.LVMSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{lvm.LVMVolKey: volName},
+			},
+			Status: lvmapi.SnapStatus{State: lvm.LVMStatusReady, OriginSize: "1073741824"},
+		}
+	}
+	cs := &controller{
+		capabilities:      newControllerCapabilities(),
+		lvmVolumeInformer: newTestInformer(t, &lvmapi.LVMVolume{}),
+		lvmSnapInformer: newTestInformer(t, &lvmapi.LVMSnapshot{},
+			snapshot("pvc-1", "snapshot-b"),
+			snapshot("pvc-1", "snapshot-a"),
+			snapshot("pvc-2", "snapshot-c"),
+		),
+	}
+
+	tests := map[string]struct {
+		req       *csi.ListSnapshotsRequest
+		snapshots []string
+		nextToken string
+		code      codes.Code
+	}{
+		"all": {
+			req:       &csi.ListSnapshotsRequest{},
+			snapshots: []string{"pvc-1@snapshot-a", "pvc-1@snapshot-b", "pvc-2@snapshot-c"},
+		},
+		"snapshot id": {
+			req:       &csi.ListSnapshotsRequest{SnapshotId: "pvc-1@snapshot-b"},
+			snapshots: []string{"pvc-1@snapshot-b"},
+		},
+		"unknown snapshot id": {
+			req: &csi.ListSnapshotsRequest{SnapshotId: "pvc-2@snapshot-a"},
+		},
+		"invalid snapshot id": {
+			req: &csi.ListSnapshotsRequest{SnapshotId: "snapshot-a"},
+		},
+		"source volume id": {
+			req:       &csi.ListSnapshotsRequest{SourceVolumeId: "PVC-1"},
+			snapshots: []string{"pvc-1@snapshot-a", "pvc-1@snapshot-b"},
+		},
+		"snapshot and other source volume id": {
+			req: &csi.ListSnapshotsRequest{SnapshotId: "pvc-1@snapshot-a", SourceVolumeId: "pvc-2"},
+		},
+		"first page": {
+			req:       &csi.ListSnapshotsRequest{MaxEntries: 2},
+			snapshots: []string{"pvc-1@snapshot-a", "pvc-1@snapshot-b"},
+			nextToken: "2",
+		},
+		"last page": {
+			req:       &csi.ListSnapshotsRequest{MaxEntries: 2, StartingToken: "2"},
+			snapshots: []string{"pvc-2@snapshot-c"},
+		},
+		"filtered page": {
+			req:       &csi.ListSnapshotsRequest{SourceVolumeId: "pvc-1", MaxEntries: 1, StartingToken: "1"},
+			snapshots: []string{"pvc-1@snapshot-b"},
+		},
+		"invalid token": {
+			req:  &csi.ListSnapshotsRequest{StartingToken: "x"},
+			code: codes.Aborted,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := cs.ListSnapshots(context.Background(), tt.req)
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err))
+				return
+			}
+			assert.NoError(t, err)
+			var snapshots []string
+			for _, entry := range resp.GetEntries() {
+				snapshots = append(snapshots, entry.GetSnapshot().GetSnapshotId())
+				assert.Equal(t, int64(Gi), entry.GetSnapshot().GetSizeBytes())
+			}
+			assert.Equal(t, tt.snapshots, snapshots)
+			assert.Equal(t, tt.nextToken, resp.GetNextToken())
+		})
+	}
+}
+
+func TestCreateSnapClone(t *testing.T) {
+	snapshot := func(originSize string) *lvmapi.LVMSnapshot {
+		return &lvmapi.LVMSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "snapshot-1",
+				Labels: map[string]string{lvm.LVMVolKey: "pvc-1"},
+			},
+			Spec:   lvmapi.LVMSnapshotSpec{OwnerNodeID: "node-1", VolGroup: "lvmvg"},
+			Status: lvmapi.SnapStatus{State: lvm.LVMStatusReady, OriginSize: originSize},
+		}
+	}
+	tests := map[string]struct {
+		snap     *lvmapi.LVMSnapshot
+		capacity int64
+		code     codes.Code
+	}{
+		"snapshot size":            {snap: snapshot("1073741824"), capacity: Gi},
+		"less than snapshot size":  {snap: snapshot("1073741824"), capacity: Gi / 2, code: codes.OutOfRange},
+		"snapshot size not stored": {snap: snapshot(""), capacity: Gi, code: codes.OutOfRange},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			newTestResourceServer(t, map[string]map[string]interface{}{
+				"lvmsnapshots": {"snapshot-1": tt.snap},
+				"lvmvolumes": {
+					// the source volume has been resized since the snapshot
+					"pvc-1": &lvmapi.LVMVolume{
+						ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+						Spec:       lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg", Capacity: "2147483648"},
+						Status:     lvmapi.VolStatus{State: lvm.LVMStatusReady},
+					},
+					// the volume has already been restored
+					"pvc-2": &lvmapi.LVMVolume{
+						ObjectMeta: metav1.ObjectMeta{Name: "pvc-2"},
+						Spec: lvmapi.VolumeInfo{OwnerNodeID: "node-1", VolGroup: "lvmvg",
+							Capacity: "1073741824", SnapName: "snapshot-1"},
+						Status: lvmapi.VolStatus{State: lvm.LVMStatusReady},
+					},
+				},
+			})
+			req := &csi.CreateVolumeRequest{
+				Name:          "pvc-2",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: tt.capacity},
+			}
+			params, err := NewVolumeParams(map[string]string{})
+			assert.NoError(t, err)
+
+			vol, err := CreateSnapClone(context.Background(), req, params, "pvc-1@snapshot-1")
+			assert.Equal(t, tt.code, status.Code(err), err)
+			if tt.code == codes.OK {
+				assert.Equal(t, "pvc-2", vol.Name)
+			}
+		})
+	}
+}
+
+func Test_getVGCapacity(t *testing.T) {
+	gi := int64(1024 * 1024 * 1024)
+	pool := func(size, virtualSize int64) []lvmapi.ThinPool {
+		return []lvmapi.ThinPool{{
+			Name:        "lvmvg_thinpool",
+			Size:        *resource.NewQuantity(size, resource.BinarySI),
+			Free:        *resource.NewQuantity(size, resource.BinarySI),
+			VirtualSize: *resource.NewQuantity(virtualSize, resource.BinarySI),
+		}}
+	}
+	usedPool := func(free, metadataFree int64) []lvmapi.ThinPool {
+		pools := pool(10*gi, 4*gi)
+		pools[0].Free = *resource.NewQuantity(free, resource.BinarySI)
+		pools[0].MetadataSize = *resource.NewQuantity(gi, resource.BinarySI)
+		pools[0].MetadataFree = *resource.NewQuantity(metadataFree, resource.BinarySI)
+		return pools
+	}
+
+	tests := map[string]struct {
+		thinPools     []lvmapi.ThinPool
+		thinProvision string
+		ratio         float64
+		threshold     int
+		expected      int64
+	}{
+		"thick volume":                 {thinPools: pool(10*gi, 0), thinProvision: "no", ratio: 1, expected: 2 * gi},
+		"thin volume without pool":     {thinProvision: "yes", ratio: 1, expected: 2 * gi},
+		"thin volume without pool x2":  {thinProvision: "yes", ratio: 2, expected: 4 * gi},
+		"thin volume with pool":        {thinPools: pool(10*gi, 4*gi), thinProvision: "yes", ratio: 1, expected: 8 * gi},
+		"thin volume with pool x3":     {thinPools: pool(10*gi, 4*gi), thinProvision: "yes", ratio: 3, expected: 32 * gi},
+		"thin pool of first volume":    {thinPools: pool(gi, gi), thinProvision: "yes", ratio: 1, expected: 2 * gi},
+		"thin volume with full pool":   {thinPools: pool(10*gi, 12*gi), thinProvision: "yes", ratio: 1, expected: 0},
+		"thin volume with other pools": {thinPools: []lvmapi.ThinPool{{Name: "otherpool"}}, thinProvision: "yes", ratio: 1, expected: 2 * gi},
+		"thin pool below threshold":    {thinPools: usedPool(2*gi, gi/2), thinProvision: "yes", ratio: 1, threshold: 90, expected: 8 * gi},
+		"thin pool data threshold":     {thinPools: usedPool(gi, gi/2), thinProvision: "yes", ratio: 1, threshold: 90, expected: 0},
+		"thin pool metadata threshold": {thinPools: usedPool(2*gi, gi/20), thinProvision: "yes", ratio: 1, threshold: 90, expected: 0},
+		"thick volume above threshold": {thinPools: usedPool(0, 0), thinProvision: "no", ratio: 1, threshold: 90, expected: 2 * gi},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			vg := &lvmapi.VolumeGroup{
+				Name:      "lvmvg",
+				Free:      *resource.NewQuantity(2*gi, resource.BinarySI),
+				ThinPools: test.thinPools,
+			}
+			params := &VolumeParams{ThinProvision: test.thinProvision,
+				OverProvisioningRatio: test.ratio, ThinPoolThreshold: 100}
+			if test.threshold != 0 {
+				params.ThinPoolThreshold = test.threshold
+			}
+			assert.Equal(t, test.expected, getVGCapacity(vg, params))
+		})
+	}
+}
+
+func TestGetCapacity(t *testing.T) {
+	namespace := lvm.LvmNamespace
+	lvm.LvmNamespace = "openebs"
+	defer func() { lvm.LvmNamespace = namespace }()
+
+	lvmNode := func(name string, free int64, pools ...lvmapi.ThinPool) runtime.Object {
+		return &lvmapi.LVMNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openebs"},
+			VolumeGroups: []lvmapi.VolumeGroup{{
+				Name:      "lvmvg",
+				Free:      *resource.NewQuantity(free, resource.BinarySI),
+				ThinPools: pools,
+			}},
+		}
+	}
+	cs := &controller{
+		k8sNodeInformer: newTestInformer(t, &corev1.Node{},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		),
+		lvmNodeInformer: newTestInformer(t, &lvmapi.LVMNode{},
+			lvmNode("node-1", 3*Gi),
+			// the thin pool has just been created for its first volume
+			lvmNode("node-2", 4*Gi, lvmapi.ThinPool{
+				Name:        "lvmvg_thinpool",
+				Size:        *resource.NewQuantity(Gi, resource.BinarySI),
+				Free:        *resource.NewQuantity(Gi, resource.BinarySI),
+				VirtualSize: *resource.NewQuantity(Gi, resource.BinarySI),
+			}),
+		),
+	}
+
+	tests := map[string]struct {
+		params   map[string]string
+		expected int64
+	}{
+		"thick": {params: map[string]string{"volgroup": "lvmvg"}, expected: 4 * Gi},
+		"thin":  {params: map[string]string{"volgroup": "lvmvg", "thinprovision": "yes"}, expected: 4 * Gi},
+		"thin overprovisioned": {
+			params:   map[string]string{"volgroup": "lvmvg", "thinprovision": "yes", "overprovisioningratio": "2"},
+			expected: 9 * Gi,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := cs.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: tt.params})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, resp.GetAvailableCapacity())
+			assert.Equal(t, tt.expected, resp.GetMaximumVolumeSize().GetValue())
+			assert.Equal(t, int64(Mi), resp.GetMinimumVolumeSize().GetValue())
+		})
+	}
+}
+
+func Test_rescheduleVolume(t *testing.T) {
+	vol := &lvmapi.LVMVolume{
+		Spec: lvmapi.VolumeInfo{OwnerNodeID: "node-3"},
+		Status: lvmapi.VolStatus{
+			State: "Failed",
+			Error: &lvmapi.VolumeError{Code: lvmapi.InsufficientCapacity, Message: "no space"},
+			Attempts: []lvmapi.VolumeAttempt{
+				{Node: "node-1"},
+				{Node: "node-2"},
+			},
+		},
+	}
+
+	// the failed nodes are excluded while scheduling the volume again
+	errSchedule := status.Error(codes.Internal, "scheduler failed")
+	var excluded map[string]bool
+	_, err := rescheduleVolume(context.Background(), vol, 3,
+		func(exclude map[string]bool) (string, error) {
+			excluded = exclude
+			return "", errSchedule
+		})
+	assert.Equal(t, errSchedule, err)
+	assert.Equal(t, map[string]bool{"node-1": true, "node-2": true, "node-3": true}, excluded)
+
+	// the volume is not rescheduled once the limit is reached
+	_, err = rescheduleVolume(context.Background(), vol, 2,
+		func(map[string]bool) (string, error) {
+			t.Fatal("volume rescheduled beyond the limit")
+			return "", nil
+		})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func Test_getVGFreeSpace(t *testing.T) {
+	vg := &lvmapi.VolumeGroup{
+		Name: "lvmvg",
+		Free: *resource.NewQuantity(2*Gi, resource.BinarySI),
+		ThinPools: []lvmapi.ThinPool{{
+			Name:        "lvmvg_thinpool",
+			Size:        *resource.NewQuantity(10*Gi, resource.BinarySI),
+			Free:        *resource.NewQuantity(5*Gi, resource.BinarySI),
+			VirtualSize: *resource.NewQuantity(4*Gi, resource.BinarySI),
+		}},
+	}
+	thick := &VolumeParams{ThinProvision: "no", OverProvisioningRatio: 1, ThinPoolThreshold: 100}
+	assert.Equal(t, int64(2*Gi), getVGFreeSpace(vg, thick))
+
+	// 20Gi left in the pool grown to 12Gi overprovisioned twice, half of which is used
+	thin := &VolumeParams{ThinProvision: "yes", OverProvisioningRatio: 2, ThinPoolThreshold: 100}
+	assert.Equal(t, int64(10*Gi), getVGFreeSpace(vg, thin))
+
+	thin.ThinPoolThreshold = 50
+	assert.Equal(t, int64(0), getVGFreeSpace(vg, thin))
+
+	// the pool created with the size of its first volume is
+	// scored by the free space of the volume group it grows into
+	exact := &lvmapi.VolumeGroup{
+		Name: "lvmvg",
+		Free: *resource.NewQuantity(20*Gi, resource.BinarySI),
+		ThinPools: []lvmapi.ThinPool{{
+			Name:        "lvmvg_thinpool",
+			Size:        *resource.NewQuantity(10*Gi, resource.BinarySI),
+			Free:        *resource.NewQuantity(10*Gi, resource.BinarySI),
+			VirtualSize: *resource.NewQuantity(10*Gi, resource.BinarySI),
+		}},
+	}
+	thin = &VolumeParams{ThinProvision: "yes", OverProvisioningRatio: 1, ThinPoolThreshold: 100}
+	assert.Equal(t, int64(20*Gi), getVGFreeSpace(exact, thin))
+}
@@ -33,6 +33,7 @@ type CSIDriver struct {
 	ids    csi.IdentityServer
 	ns     csi.NodeServer
 	cs     csi.ControllerServer
+	gcs    csi.GroupControllerServer
 
 	cap []*csi.VolumeCapability_AccessMode
 }
@@ -42,6 +43,9 @@ type CSIDriver struct {
 func GetVolumeCapabilityAccessModes() []*csi.VolumeCapability_AccessMode {
 	supported := []csi.VolumeCapability_AccessMode_Mode{
 		csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER,
 	}
 
 	var vcams []*csi.VolumeCapability_AccessMode
@@ -66,6 +70,8 @@ func New(config *config.Config) *CSIDriver {
 	switch config.PluginType {
 	case "controller":
 		driver.cs = NewController(driver)
+		// the controller also serves the group snapshots
+		driver.gcs = driver.cs.(csi.GroupControllerServer)
 
 	case "agent":
 		// Start monitor goroutine to monitor the
@@ -88,7 +94,7 @@ func New(config *config.Config) *CSIDriver {
 // over the given endpoint
 func (d *CSIDriver) Run() error {
 	// Initialize and start listening on grpc server
-	s := NewNonBlockingGRPCServer(d.config.Endpoint, d.ids, d.cs, d.ns)
+	s := NewNonBlockingGRPCServer(d.config.Endpoint, d.ids, d.cs, d.gcs, d.ns)
 
 	s.Start()
 	s.Wait()
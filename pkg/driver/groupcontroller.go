@@ -0,0 +1,285 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/builder/snapgroupbuilder"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// GroupControllerGetCapabilities fetches the group controller capabilities
+//
+// This implements csi.GroupControllerServer
+func (cs *controller) GroupControllerGetCapabilities(
+	ctx context.Context,
+	req *csi.GroupControllerGetCapabilitiesRequest,
+) (*csi.GroupControllerGetCapabilitiesResponse, error) {
+
+	return &csi.GroupControllerGetCapabilitiesResponse{
+		Capabilities: []*csi.GroupControllerServiceCapability{
+			{
+				Type: &csi.GroupControllerServiceCapability_Rpc{
+					Rpc: &csi.GroupControllerServiceCapability_RPC{
+						Type: csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// CreateVolumeGroupSnapshot creates the snapshots of the given volumes at
+// the same point in time through a LVMSnapshotGroup, the volumes have to
+// be present on the same node
+//
+// This implements csi.GroupControllerServer
+func (cs *controller) CreateVolumeGroupSnapshot(
+	ctx context.Context,
+	req *csi.CreateVolumeGroupSnapshotRequest,
+) (*csi.CreateVolumeGroupSnapshotResponse, error) {
+
+	klog.Infof("CreateVolumeGroupSnapshot %s for %v", req.Name, req.SourceVolumeIds)
+
+	groupName := strings.ToLower(req.GetName())
+	if groupName == "" || len(req.GetSourceVolumeIds()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"CreateVolumeGroupSnapshot error invalid request %s: %v",
+			groupName, req.GetSourceVolumeIds())
+	}
+
+	volumes := make([]string, 0, len(req.GetSourceVolumeIds()))
+	seen := make(map[string]bool)
+	for _, id := range req.GetSourceVolumeIds() {
+		id = strings.ToLower(id)
+		if seen[id] {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"CreateVolumeGroupSnapshot error volume %s specified more than once", id)
+		}
+		seen[id] = true
+		volumes = append(volumes, id)
+	}
+
+	group, err := lvm.GetLVMSnapshotGroup(groupName)
+	if err == nil {
+		if !sameVolumes(group.Spec.Volumes, volumes) {
+			return nil, status.Errorf(codes.AlreadyExists,
+				"CreateVolumeGroupSnapshot error group snapshot %s already exists for volumes %v",
+				groupName, group.Spec.Volumes)
+		}
+		// a failed group is not retried by the node agent, delete it
+		// so that it gets created again on the next request
+		if group.Status.State == lvm.LVMStatusFailed {
+			if err = lvm.DeleteSnapshotGroup(groupName); err != nil && !k8serror.IsNotFound(err) {
+				return nil, status.Errorf(codes.Internal,
+					"CreateVolumeGroupSnapshot failed to delete failed group snapshot %s: %v", groupName, err)
+			}
+			return nil, status.Errorf(codes.Internal,
+				"CreateVolumeGroupSnapshot group snapshot %s failed: %s", groupName, group.Status.Error)
+		}
+		return cs.newCreateVolumeGroupSnapshotResponse(group)
+	}
+	if !k8serror.IsNotFound(err) {
+		return nil, status.Errorf(codes.Internal,
+			"CreateVolumeGroupSnapshot failed to get group snapshot %s: %v", groupName, err)
+	}
+
+	var ownerNode string
+	for _, id := range volumes {
+		vol, err := lvm.GetLVMVolume(id)
+		if err != nil {
+			if k8serror.IsNotFound(err) {
+				return nil, status.Errorf(codes.NotFound,
+					"CreateVolumeGroupSnapshot volume %s not found", id)
+			}
+			return nil, status.Errorf(codes.Internal,
+				"CreateVolumeGroupSnapshot not able to get volume %s: %v", id, err)
+		}
+		if ownerNode == "" {
+			ownerNode = vol.Spec.OwnerNodeID
+		} else if vol.Spec.OwnerNodeID != ownerNode {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"CreateVolumeGroupSnapshot volume %s is present on node %s, not on %s",
+				id, vol.Spec.OwnerNodeID, ownerNode)
+		}
+	}
+
+	group, err = snapgroupbuilder.NewBuilder().
+		WithName(groupName).
+		WithLabels(lvm.DriverLabels()).
+		WithOwnerNode(ownerNode).
+		WithVolumes(volumes).
+		WithSnapGroupStatus(lvm.LVMStatusPending).
+		Build()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to create group snapshot object %s: %v", groupName, err)
+	}
+
+	if err = lvm.ProvisionSnapshotGroup(group); err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to handle CreateVolumeGroupSnapshotRequest for %s: %v", groupName, err)
+	}
+
+	if created, err := lvm.GetLVMSnapshotGroup(groupName); err == nil {
+		group = created
+	}
+	return cs.newCreateVolumeGroupSnapshotResponse(group)
+}
+
+// newCreateVolumeGroupSnapshotResponse builds the
+// CreateVolumeGroupSnapshot response of the given group
+func (cs *controller) newCreateVolumeGroupSnapshotResponse(
+	group *lvmapi.LVMSnapshotGroup,
+) (*csi.CreateVolumeGroupSnapshotResponse, error) {
+	groupSnapshot, err := cs.getCSIGroupSnapshot(group)
+	if err != nil {
+		return nil, err
+	}
+	return &csi.CreateVolumeGroupSnapshotResponse{GroupSnapshot: groupSnapshot}, nil
+}
+
+// DeleteVolumeGroupSnapshot deletes the LVMSnapshotGroup, its member
+// snapshots are deleted along with it
+//
+// This implements csi.GroupControllerServer
+func (cs *controller) DeleteVolumeGroupSnapshot(
+	ctx context.Context,
+	req *csi.DeleteVolumeGroupSnapshotRequest,
+) (*csi.DeleteVolumeGroupSnapshotResponse, error) {
+
+	klog.Infof("DeleteVolumeGroupSnapshot request for %s", req.GroupSnapshotId)
+
+	groupName := strings.ToLower(req.GetGroupSnapshotId())
+	if groupName == "" {
+		return nil, status.Error(codes.InvalidArgument,
+			"DeleteVolumeGroupSnapshot error missing group snapshot id")
+	}
+
+	if err := lvm.DeleteSnapshotGroup(groupName); err != nil && !k8serror.IsNotFound(err) {
+		return nil, status.Errorf(codes.Internal,
+			"failed to handle DeleteVolumeGroupSnapshot for %s: %v", groupName, err)
+	}
+
+	return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+}
+
+// GetVolumeGroupSnapshot fetches the given group snapshot
+//
+// This implements csi.GroupControllerServer
+func (cs *controller) GetVolumeGroupSnapshot(
+	ctx context.Context,
+	req *csi.GetVolumeGroupSnapshotRequest,
+) (*csi.GetVolumeGroupSnapshotResponse, error) {
+
+	groupName := strings.ToLower(req.GetGroupSnapshotId())
+	if groupName == "" {
+		return nil, status.Error(codes.InvalidArgument,
+			"GetVolumeGroupSnapshot error missing group snapshot id")
+	}
+
+	group, err := lvm.GetLVMSnapshotGroup(groupName)
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound,
+				"GetVolumeGroupSnapshot group snapshot %s not found", groupName)
+		}
+		return nil, status.Errorf(codes.Internal,
+			"GetVolumeGroupSnapshot failed to get group snapshot %s: %v", groupName, err)
+	}
+	if group.Status.State == lvm.LVMStatusFailed {
+		return nil, status.Errorf(codes.Internal,
+			"GetVolumeGroupSnapshot group snapshot %s failed: %s", groupName, group.Status.Error)
+	}
+
+	groupSnapshot, err := cs.getCSIGroupSnapshot(group)
+	if err != nil {
+		return nil, err
+	}
+	return &csi.GetVolumeGroupSnapshotResponse{GroupSnapshot: groupSnapshot}, nil
+}
+
+// getCSIGroupSnapshot returns the csi group snapshot of the given lvm
+// snapshot group. The member snapshots are created by the node agent, until
+// then they are reported as not ready with the ids they will be created with.
+func (cs *controller) getCSIGroupSnapshot(group *lvmapi.LVMSnapshotGroup) (*csi.VolumeGroupSnapshot, error) {
+	ready := group.Status.State == lvm.LVMStatusReady
+	creationTime := timestamppb.New(group.CreationTimestamp.Time)
+
+	snapshots := make([]*csi.Snapshot, 0, len(group.Spec.Volumes))
+	for _, volName := range group.Spec.Volumes {
+		snapName := lvm.GetSnapGroupMemberName(group.Name, volName)
+
+		var snapshot *csi.Snapshot
+		snap, err := lvm.GetLVMSnapshot(snapName)
+		switch {
+		case err == nil:
+			snapshot = cs.getCSISnapshot(snap)
+		case k8serror.IsNotFound(err) && !ready:
+			snapshot = &csi.Snapshot{
+				SnapshotId:     volName + "@" + snapName,
+				SourceVolumeId: volName,
+				CreationTime:   creationTime,
+			}
+		default:
+			return nil, status.Errorf(codes.Internal,
+				"failed to get snapshot %s of group snapshot %s: %v", snapName, group.Name, err)
+		}
+		snapshot.GroupSnapshotId = group.Name
+		snapshots = append(snapshots, snapshot)
+	}
+
+	// the snapshots of the group are all taken at the same point in time
+	if ready && len(snapshots) != 0 {
+		creationTime = snapshots[0].CreationTime
+	}
+
+	return &csi.VolumeGroupSnapshot{
+		GroupSnapshotId: group.Name,
+		Snapshots:       snapshots,
+		CreationTime:    creationTime,
+		ReadyToUse:      ready,
+	}, nil
+}
+
+// sameVolumes checks whether both the lists have the same volumes
+func sameVolumes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// newTestResourceServer serves the requests of the default clients for the
+// lvm resources with the given objects, keyed by resource and name. It
+// returns the objects, updated with the ones created and deleted.
+func newTestResourceServer(t *testing.T, objs map[string]map[string]interface{}) map[string]map[string]interface{} {
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		// /apis/<group>/<version>/namespaces/<namespace>/<resource>[/<name>]
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if len(parts) < 6 {
+			http.NotFound(w, r)
+			return
+		}
+		resource, name := parts[5], ""
+		if len(parts) > 6 {
+			name = parts[6]
+		}
+		if objs[resource] == nil {
+			objs[resource] = map[string]interface{}{}
+		}
+
+		var obj interface{}
+		code := http.StatusOK
+		switch r.Method {
+		case http.MethodPost:
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			meta := &metav1.ObjectMeta{}
+			assert.NoError(t, json.Unmarshal(body, &struct {
+				Meta *metav1.ObjectMeta `json:"metadata"`
+			}{meta}))
+			objs[resource][meta.Name] = json.RawMessage(body)
+			obj, code = json.RawMessage(body), http.StatusCreated
+		case http.MethodGet, http.MethodDelete:
+			found, ok := objs[resource][name]
+			if !ok {
+				status := k8serror.NewNotFound(schema.GroupResource{
+					Group: lvmapi.SchemeGroupVersion.Group, Resource: resource}, name).ErrStatus
+				obj, code = &status, http.StatusNotFound
+				break
+			}
+			obj = found
+			if r.Method == http.MethodDelete {
+				delete(objs[resource], name)
+				obj = &metav1.Status{Status: metav1.StatusSuccess}
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(code)
+		assert.NoError(t, json.NewEncoder(w).Encode(obj))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENEBS_IO_K8S_MASTER", srv.URL)
+
+	namespace := lvm.LvmNamespace
+	lvm.LvmNamespace = "openebs"
+	t.Cleanup(func() { lvm.LvmNamespace = namespace })
+	return objs
+}
+
+func TestCreateVolumeGroupSnapshot(t *testing.T) {
+	volume := func(name, node string) *lvmapi.LVMVolume {
+		return &lvmapi.LVMVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       lvmapi.VolumeInfo{OwnerNodeID: node, VolGroup: "lvmvg", Capacity: "1073741824"},
+			Status:     lvmapi.VolStatus{State: lvm.LVMStatusReady},
+		}
+	}
+	group := func(state string, vols ...string) *lvmapi.LVMSnapshotGroup {
+		return &lvmapi.LVMSnapshotGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "group"},
+			Spec:       lvmapi.LVMSnapshotGroupSpec{OwnerNodeID: "node-1", Volumes: vols},
+			Status:     lvmapi.SnapGroupStatus{State: state},
+		}
+	}
+	snapshot := func(vol string) *lvmapi.LVMSnapshot {
+		return &lvmapi.LVMSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "group-" + vol,
+				Labels: map[string]string{lvm.LVMVolKey: vol, lvm.LVMSnapGroupKey: "group"},
+			},
+			Status: lvmapi.SnapStatus{State: lvm.LVMStatusReady, OriginSize: "1073741824"},
+		}
+	}
+
+	tests := map[string]struct {
+		req       *csi.CreateVolumeGroupSnapshotRequest
+		groups    []*lvmapi.LVMSnapshotGroup
+		snapshots []*lvmapi.LVMSnapshot
+		code      codes.Code
+		ready     bool
+		deleted   bool
+	}{
+		"missing name": {
+			req:  &csi.CreateVolumeGroupSnapshotRequest{SourceVolumeIds: []string{"pvc-1"}},
+			code: codes.InvalidArgument,
+		},
+		"missing volumes": {
+			req:  &csi.CreateVolumeGroupSnapshotRequest{Name: "group"},
+			code: codes.InvalidArgument,
+		},
+		"duplicate volumes": {
+			req:  &csi.CreateVolumeGroupSnapshotRequest{Name: "group", SourceVolumeIds: []string{"pvc-1", "pvc-1"}},
+			code: codes.InvalidArgument,
+		},
+		"unknown volume": {
+			req:  &csi.CreateVolumeGroupSnapshotRequest{Name: "group", SourceVolumeIds: []string{"pvc-1", "pvc-9"}},
+			code: codes.NotFound,
+		},
+		"volumes on different nodes": {
+			req:  &csi.CreateVolumeGroupSnapshotRequest{Name: "group", SourceVolumeIds: []string{"pvc-1", "pvc-3"}},
+			code: codes.InvalidArgument,
+		},
+		"creates the group": {
+			req: &csi.CreateVolumeGroupSnapshotRequest{Name: "Group", SourceVolumeIds: []string{"pvc-1", "pvc-2"}},
+		},
+		"existing group": {
+			req:       &csi.CreateVolumeGroupSnapshotRequest{Name: "group", SourceVolumeIds: []string{"pvc-2", "pvc-1"}},
+			groups:    []*lvmapi.LVMSnapshotGroup{group(lvm.LVMStatusReady, "pvc-1", "pvc-2")},
+			snapshots: []*lvmapi.LVMSnapshot{snapshot("pvc-1"), snapshot("pvc-2")},
+			ready:     true,
+		},
+		"existing group of other volumes": {
+			req:    &csi.CreateVolumeGroupSnapshotRequest{Name: "group", SourceVolumeIds: []string{"pvc-1"}},
+			groups: []*lvmapi.LVMSnapshotGroup{group(lvm.LVMStatusReady, "pvc-1", "pvc-2")},
+			code:   codes.AlreadyExists,
+		},
+		"failed group": {
+			req:     &csi.CreateVolumeGroupSnapshotRequest{Name: "group", SourceVolumeIds: []string{"pvc-1", "pvc-2"}},
+			groups:  []*lvmapi.LVMSnapshotGroup{group(lvm.LVMStatusFailed, "pvc-1", "pvc-2")},
+			code:    codes.Internal,
+			deleted: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			objs := map[string]map[string]interface{}{
+				"lvmvolumes": {
+					"pvc-1": volume("pvc-1", "node-1"),
+					"pvc-2": volume("pvc-2", "node-1"),
+					"pvc-3": volume("pvc-3", "node-2"),
+				},
+				"lvmsnapshotgroups": {},
+				"lvmsnapshots":      {},
+			}
+			for _, g := range tt.groups {
+				objs["lvmsnapshotgroups"][g.Name] = g
+			}
+			for _, s := range tt.snapshots {
+				objs["lvmsnapshots"][s.Name] = s
+			}
+			objs = newTestResourceServer(t, objs)
+			cs := &controller{lvmVolumeInformer: newTestInformer(t, &lvmapi.LVMVolume{})}
+
+			resp, err := cs.CreateVolumeGroupSnapshot(context.Background(), tt.req)
+			assert.Equal(t, tt.code, status.Code(err), err)
+			if tt.deleted {
+				assert.NotContains(t, objs["lvmsnapshotgroups"], "group")
+			}
+			if tt.code != codes.OK {
+				return
+			}
+
+			assert.Contains(t, objs["lvmsnapshotgroups"], "group")
+			created := &lvmapi.LVMSnapshotGroup{}
+			raw, _ := json.Marshal(objs["lvmsnapshotgroups"]["group"])
+			assert.NoError(t, json.Unmarshal(raw, created))
+			assert.Equal(t, "node-1", created.Spec.OwnerNodeID)
+			assert.NotEqual(t, lvm.LVMStatusFailed, created.Status.State)
+			assert.ElementsMatch(t, []string{"pvc-1", "pvc-2"}, created.Spec.Volumes)
+
+			groupSnapshot := resp.GetGroupSnapshot()
+			assert.Equal(t, "group", groupSnapshot.GroupSnapshotId)
+			assert.Equal(t, tt.ready, groupSnapshot.ReadyToUse)
+			var ids []string
+			for _, snap := range groupSnapshot.Snapshots {
+				assert.Equal(t, "group", snap.GroupSnapshotId)
+				assert.Equal(t, tt.ready, snap.ReadyToUse)
+				ids = append(ids, snap.SnapshotId)
+			}
+			assert.ElementsMatch(t, []string{"pvc-1@group-pvc-1", "pvc-2@group-pvc-2"}, ids)
+		})
+	}
+}
+
+func TestGetDeleteVolumeGroupSnapshot(t *testing.T) {
+	objs := newTestResourceServer(t, map[string]map[string]interface{}{
+		"lvmsnapshotgroups": {
+			"group": &lvmapi.LVMSnapshotGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "group"},
+				Spec:       lvmapi.LVMSnapshotGroupSpec{OwnerNodeID: "node-1", Volumes: []string{"pvc-1"}},
+				Status:     lvmapi.SnapGroupStatus{State: lvm.LVMStatusPending},
+			},
+		},
+	})
+	cs := &controller{}
+
+	_, err := cs.GetVolumeGroupSnapshot(context.Background(), &csi.GetVolumeGroupSnapshotRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = cs.GetVolumeGroupSnapshot(context.Background(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: "other"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	resp, err := cs.GetVolumeGroupSnapshot(context.Background(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: "group"})
+	assert.NoError(t, err)
+	assert.False(t, resp.GetGroupSnapshot().ReadyToUse)
+
+	_, err = cs.DeleteVolumeGroupSnapshot(context.Background(), &csi.DeleteVolumeGroupSnapshotRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = cs.DeleteVolumeGroupSnapshot(context.Background(), &csi.DeleteVolumeGroupSnapshotRequest{GroupSnapshotId: "group"})
+	assert.NoError(t, err)
+	assert.Empty(t, objs["lvmsnapshotgroups"])
+	// the group snapshot is already deleted
+	_, err = cs.DeleteVolumeGroupSnapshot(context.Background(), &csi.DeleteVolumeGroupSnapshotRequest{GroupSnapshotId: "group"})
+	assert.NoError(t, err)
+}
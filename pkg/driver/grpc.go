@@ -100,11 +100,12 @@ type NonBlockingGRPCServer interface {
 }
 
 // NewNonBlockingGRPCServer returns a new instance of NonBlockingGRPCServer
-func NewNonBlockingGRPCServer(ep string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) NonBlockingGRPCServer {
+func NewNonBlockingGRPCServer(ep string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer) NonBlockingGRPCServer {
 	return &nonBlockingGRPCServer{
 		endpoint:    ep,
 		idntyServer: ids,
 		ctrlServer:  cs,
+		groupServer: gcs,
 		agentServer: ns}
 }
 
@@ -117,6 +118,7 @@ type nonBlockingGRPCServer struct {
 	endpoint    string
 	idntyServer csi.IdentityServer
 	ctrlServer  csi.ControllerServer
+	groupServer csi.GroupControllerServer
 	agentServer csi.NodeServer
 }
 
@@ -125,7 +127,7 @@ func (s *nonBlockingGRPCServer) Start() {
 
 	s.wg.Add(1)
 
-	go s.serve(s.endpoint, s.idntyServer, s.ctrlServer, s.agentServer)
+	go s.serve(s.endpoint, s.idntyServer, s.ctrlServer, s.groupServer, s.agentServer)
 }
 
 // Wait for the service to stop
@@ -146,7 +148,7 @@ func (s *nonBlockingGRPCServer) ForceStop() {
 // serve starts serving requests at the provided endpoint based on the type of
 // plugin. In this function all the csi related interfaces are provided by
 // container-storage-interface
-func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer) {
 
 	proto, addr, err := parseEndpoint(endpoint)
 	if err != nil {
@@ -183,6 +185,9 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 	if cs != nil {
 		csi.RegisterControllerServer(server, cs)
 	}
+	if gcs != nil {
+		csi.RegisterGroupControllerServer(server, gcs)
+	}
 	if ns != nil {
 		csi.RegisterNodeServer(server, ns)
 	}
@@ -27,6 +27,8 @@ import (
 // identity is the server implementation
 // for CSI IdentityServer
 type identity struct {
+	csi.UnimplementedIdentityServer
+
 	driver *CSIDriver
 }
 
@@ -107,6 +109,13 @@ func (id *identity) GetPluginCapabilities(
 					},
 				},
 			},
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE,
+					},
+				},
+			},
 		},
 	}, nil
 }
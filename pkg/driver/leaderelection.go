@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/openebs/lib-csi/pkg/common/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// leaderElectionLeaseName returns the name of the lease used for the
+// leader election among the controller plugin replicas of the driver.
+func leaderElectionLeaseName(driverName string) string {
+	return "lvm-controller-" + strings.ReplaceAll(driverName, ".", "-")
+}
+
+// leakProtector protects the volumes being provisioned from leaking when
+// their PVC gets deleted meanwhile, see csipv.LeakProtectionController.
+type leakProtector interface {
+	BeginCreateVolume(volumeName, pvcNamespace, pvcName string) (func(), error)
+	Run(workers int, stopCh <-chan struct{})
+}
+
+// startLeading starts the controllers which must run only on the leader.
+func (cs *controller) startLeading(stopCh <-chan struct{}) {
+	klog.Info("started leading, running the lvm controller")
+	go cs.leakProtection.Run(2, stopCh)
+}
+
+// runLeaderElection runs the lease based leader election among the
+// controller plugin replicas until the stop channel is closed. The
+// informers keep running on the standby replicas, so that their caches
+// are warm when one of them takes over the leadership.
+func (cs *controller) runLeaderElection(kubeClient kubernetes.Interface,
+	stopCh <-chan struct{}) error {
+	cfg := cs.driver.config
+
+	identity, err := os.Hostname()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the leader election identity")
+	}
+
+	namespace := cfg.LeaderElectionNamespace
+	if namespace == "" {
+		namespace = lvm.LvmNamespace
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaderElectionLeaseName(cfg.DriverName),
+			Namespace: namespace,
+		},
+		Client:     kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            lock.LeaseMeta.Name,
+		LeaseDuration:   cfg.LeaderElectionLeaseDuration,
+		RenewDeadline:   cfg.LeaderElectionRenewDeadline,
+		RetryPeriod:     cfg.LeaderElectionRetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				cs.startLeading(ctx.Done())
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					klog.Infof("stopped leading, shutting down")
+					return
+				}
+				// the controllers started on becoming the leader can not
+				// be restarted, so exit and come back as a standby.
+				klog.Fatalf("lost the leadership of %s/%s", namespace, lock.LeaseMeta.Name)
+			},
+			OnNewLeader: func(leader string) {
+				klog.Infof("new leader elected for %s/%s: %s",
+					namespace, lock.LeaseMeta.Name, leader)
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create the leader elector")
+	}
+
+	klog.Infof("starting leader election for %s/%s as %s",
+		namespace, lock.LeaseMeta.Name, identity)
+	go elector.Run(ctx)
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openebs/lvm-localpv/pkg/driver/config"
+)
+
+// fakeLeakProtector records whether the leak protection controller runs.
+type fakeLeakProtector struct {
+	running atomic.Bool
+}
+
+func (f *fakeLeakProtector) BeginCreateVolume(volumeName,
+	pvcNamespace, pvcName string) (func(), error) {
+	return func() {}, nil
+}
+
+func (f *fakeLeakProtector) Run(workers int, stopCh <-chan struct{}) {
+	f.running.Store(true)
+	<-stopCh
+	f.running.Store(false)
+}
+
+// createVolumeCode returns the code of a CreateVolume request which
+// fails parsing its params.
+func createVolumeCode(cs *controller) codes.Code {
+	_, err := cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name: "pvc-1",
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		}},
+		Parameters: map[string]string{"scheduler": "unknown"},
+	})
+	return status.Code(err)
+}
+
+func TestStartLeading(t *testing.T) {
+	leakProtection := &fakeLeakProtector{}
+	cs := &controller{capabilities: newControllerCapabilities(), leakProtection: leakProtection}
+	stopCh := make(chan struct{})
+	cs.startLeading(stopCh)
+	assert.Eventually(t, leakProtection.running.Load, time.Second, 10*time.Millisecond)
+	close(stopCh)
+	assert.Eventually(t, func() bool { return !leakProtection.running.Load() }, time.Second, 10*time.Millisecond)
+}
+
+func TestRunLeaderElection(t *testing.T) {
+	cfg := &config.Config{
+		DriverName:                  "local.csi.openebs.io",
+		LeaderElection:              true,
+		LeaderElectionNamespace:     "openebs",
+		LeaderElectionLeaseDuration: 300 * time.Millisecond,
+		LeaderElectionRenewDeadline: 200 * time.Millisecond,
+		LeaderElectionRetryPeriod:   20 * time.Millisecond,
+	}
+	// the lease is held by another replica
+	holder := "other-replica"
+	duration := int32(3600)
+	renewTime := metav1.NewMicroTime(time.Now())
+	kubeClient := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      leaderElectionLeaseName(cfg.DriverName),
+			Namespace: cfg.LeaderElectionNamespace,
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &renewTime,
+			RenewTime:            &renewTime,
+		},
+	})
+
+	leakProtection := &fakeLeakProtector{}
+	cs := &controller{
+		driver:         &CSIDriver{config: cfg},
+		capabilities:   newControllerCapabilities(),
+		leakProtection: leakProtection,
+	}
+	stopCh := make(chan struct{})
+	assert.NoError(t, cs.runLeaderElection(kubeClient, stopCh))
+
+	// the standby replica doesn't run the leak protection controller,
+	// but still serves the requests of its csi-provisioner.
+	time.Sleep(5 * cfg.LeaderElectionRetryPeriod)
+	assert.False(t, leakProtection.running.Load())
+	assert.Equal(t, codes.InvalidArgument, createVolumeCode(cs))
+
+	// the other replica goes away and its lease expires.
+	leases := kubeClient.CoordinationV1().Leases(cfg.LeaderElectionNamespace)
+	assert.NoError(t, leases.Delete(context.Background(),
+		leaderElectionLeaseName(cfg.DriverName), metav1.DeleteOptions{}))
+
+	assert.Eventually(t, leakProtection.running.Load, 2*time.Second, 10*time.Millisecond)
+
+	// the leadership is released on shutdown.
+	close(stopCh)
+	assert.Eventually(t, func() bool { return !leakProtection.running.Load() }, 2*time.Second, 10*time.Millisecond)
+}
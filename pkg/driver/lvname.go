@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	// lvNameHashLen is the number of hex characters of the
+	// hash of the volume name available to the templates.
+	lvNameHashLen = 8
+
+	// maxLVNameLen is the maximum length of a rendered logical volume
+	// name, counting the hyphens twice as they are doubled in the device
+	// mapper name. LVM limits the device mapper name, made of the volume
+	// group and the logical volume names, to 127 characters, so the rest
+	// is left for the volume group name and the suffixes added by LVM.
+	maxLVNameLen = 64
+)
+
+var (
+	// lvNameInvalidChars matches the characters not allowed by LVM
+	// in the logical volume names.
+	lvNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9+_.-]`)
+
+	// lvNameReservedPrefixes are the prefixes reserved by LVM
+	// for the logical volume names.
+	lvNameReservedPrefixes = []string{"snapshot", "pvmove"}
+
+	// lvNameReservedSubstrings are the substrings reserved by LVM
+	// for its internal logical volumes.
+	lvNameReservedSubstrings = []string{
+		"_cdata", "_cmeta", "_corig", "_iorig", "_mimage", "_mlog",
+		"_pmspare", "_rimage", "_rmeta", "_tdata", "_tmeta",
+		"_vdata", "_vorigin", "_wcorig",
+	}
+)
+
+// lvNameData is the data available to the logical volume name templates.
+type lvNameData struct {
+	PVCNamespace string
+	PVCName      string
+	PVName       string
+	// Hash is the truncated hex encoded sha256 hash of the volume name.
+	Hash string
+}
+
+// parseLVNameTemplate parses the logical volume name template set
+// in the storage class.
+func parseLVNameTemplate(text string) (*template.Template, error) {
+	return template.New("lvname").Parse(text)
+}
+
+// renderLVName renders the logical volume name of the volume from the
+// template of the storage class. The characters not allowed by LVM are
+// replaced with "-" and the names exceeding maxLVNameLen are truncated,
+// keeping the hash at the end so that they remain unique. An empty name
+// is returned if the storage class has no template, in which case the
+// name of the volume is used as the logical volume name.
+func (p *VolumeParams) renderLVName(volName string) (string, error) {
+	if p.LVNameTemplate == nil {
+		return "", nil
+	}
+	// the pvc name and namespace are passed only when the
+	// external provisioner runs with --extra-create-metadata.
+	if p.PVCName == "" || p.PVCNamespace == "" {
+		return "", fmt.Errorf("lvNameTemplate requires the pvc metadata, " +
+			"enable --extra-create-metadata in the csi-provisioner")
+	}
+
+	data := lvNameData{
+		PVCNamespace: p.PVCNamespace,
+		PVCName:      p.PVCName,
+		PVName:       volName,
+		Hash:         lvNameHash(volName),
+	}
+
+	var sb strings.Builder
+	if err := p.LVNameTemplate.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render lvNameTemplate: %v", err)
+	}
+	rendered := sb.String()
+
+	// the name of a new volume must not match the logical volume of
+	// a retained one having the same pvc name and namespace.
+	if !strings.Contains(rendered, data.Hash) && !strings.Contains(rendered, data.PVName) {
+		return "", fmt.Errorf("lvNameTemplate must include {{ .Hash }} or {{ .PVName }}")
+	}
+
+	name := strings.TrimLeft(lvNameInvalidChars.ReplaceAllString(rendered, "-"), "-")
+	if lvNameLen(name) > maxLVNameLen {
+		suffix := "-" + data.Hash
+		name = truncateLVName(name, maxLVNameLen-lvNameLen(suffix))
+		name = strings.TrimRight(name, "-") + suffix
+	}
+
+	if err := validateLVName(name); err != nil {
+		return "", fmt.Errorf("invalid lv name %q rendered from lvNameTemplate: %v", name, err)
+	}
+	return name, nil
+}
+
+// lvNameHash returns the truncated hex encoded sha256 hash of the volume name.
+func lvNameHash(volName string) string {
+	sum := sha256.Sum256([]byte(volName))
+	return hex.EncodeToString(sum[:])[:lvNameHashLen]
+}
+
+// lvNameLen returns the length of the name in the device mapper name.
+func lvNameLen(name string) int {
+	return len(name) + strings.Count(name, "-")
+}
+
+// truncateLVName truncates the name to fit in the given length.
+func truncateLVName(name string, length int) string {
+	for i := range name {
+		if lvNameLen(name[:i+1]) > length {
+			return name[:i]
+		}
+	}
+	return name
+}
+
+// validateLVName checks the name against the logical volume
+// name restrictions of LVM.
+func validateLVName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("name is empty or a relative path")
+	}
+	for _, prefix := range lvNameReservedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("prefix %q is reserved by lvm", prefix)
+		}
+	}
+	for _, substr := range lvNameReservedSubstrings {
+		if strings.Contains(name, substr) {
+			return fmt.Errorf("%q is reserved by lvm", substr)
+		}
+	}
+	return nil
+}
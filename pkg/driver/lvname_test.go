@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderLVName(t *testing.T) {
+	const volName = "pvc-1b4b3c9e-2f3a-4c11-9a6e-3b1c7d0e5f42"
+	hash := lvNameHash(volName)
+
+	tests := map[string]struct {
+		template     string
+		pvcNamespace string
+		pvcName      string
+		want         string
+		wantErr      bool
+	}{
+		"no template": {
+			want: "",
+		},
+		"namespace, name and hash": {
+			template:     "{{ .PVCNamespace }}_{{ .PVCName }}_{{ .Hash }}",
+			pvcNamespace: "prod",
+			pvcName:      "data-db-0",
+			want:         "prod_data-db-0_" + hash,
+		},
+		"pv name": {
+			template:     "{{ .PVCName }}.{{ .PVName }}",
+			pvcNamespace: "prod",
+			pvcName:      "data",
+			want:         "data." + volName,
+		},
+		"invalid characters and leading hyphens": {
+			template:     "--{{ .PVCName }}/{{ .Hash }}",
+			pvcNamespace: "prod",
+			pvcName:      "data",
+			want:         "data-" + hash,
+		},
+		"long names are truncated keeping the hash": {
+			template:     "{{ .PVCNamespace }}-{{ .PVCName }}-{{ .Hash }}",
+			pvcNamespace: "prod",
+			pvcName:      strings.Repeat("a", 100),
+			want:         "prod-" + strings.Repeat("a", 48) + "-" + hash,
+		},
+		"not unique": {
+			template:     "{{ .PVCNamespace }}-{{ .PVCName }}",
+			pvcNamespace: "prod",
+			pvcName:      "data",
+			wantErr:      true,
+		},
+		"reserved prefix": {
+			template:     "snapshot-{{ .Hash }}",
+			pvcNamespace: "prod",
+			pvcName:      "data",
+			wantErr:      true,
+		},
+		"reserved substring": {
+			template:     "{{ .PVCName }}_tmeta-{{ .Hash }}",
+			pvcNamespace: "prod",
+			pvcName:      "data",
+			wantErr:      true,
+		},
+		"unknown field": {
+			template:     "{{ .StorageClass }}-{{ .Hash }}",
+			pvcNamespace: "prod",
+			pvcName:      "data",
+			wantErr:      true,
+		},
+		"missing pvc metadata": {
+			template: "{{ .PVCName }}-{{ .Hash }}",
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			params := &VolumeParams{
+				PVCNamespace: tt.pvcNamespace,
+				PVCName:      tt.pvcName,
+			}
+			if tt.template != "" {
+				var err error
+				params.LVNameTemplate, err = parseLVNameTemplate(tt.template)
+				assert.NoError(t, err)
+			}
+
+			got, err := params.renderLVName(volName)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.LessOrEqual(t, lvNameLen(got), maxLVNameLen)
+		})
+	}
+}
@@ -21,9 +21,23 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/openebs/lib-csi/pkg/common/helpers"
 	"k8s.io/apimachinery/pkg/api/resource"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+const (
+	// AllowedOverridesKey is the storage class parameter listing the
+	// comma separated parameters which can be overridden per PVC.
+	AllowedOverridesKey = "allowedoverrides"
+
+	// PVCOverridePrefix is the prefix of the PVC annotations overriding
+	// the storage class parameters, e.g. lvm.openebs.io/vgpattern.
+	PVCOverridePrefix = "lvm.openebs.io/"
 )
 
 // VolumeParams holds collection of supported settings that can
@@ -33,9 +47,63 @@ type VolumeParams struct {
 	// provisioning logical volumes.
 	VgPattern *regexp.Regexp
 
-	Scheduler     string
+	// Scheduler computes the weights of the nodes where
+	// the volume can be provisioned.
+	Scheduler NodeScheduler
+
 	Shared        string
 	ThinProvision string
+
+	// OverProvisioningRatio specifies how many times the size of a
+	// thin pool can be allocated to the thin volumes created from it.
+	OverProvisioningRatio float64
+
+	// ThinPoolThreshold specifies the usage percentage of the data or
+	// metadata of a thin pool from which no more thin volumes are
+	// allocated from it.
+	ThinPoolThreshold int
+
+	// DeletionPolicy specifies how the deletion of a volume
+	// is handled when it still has snapshots.
+	DeletionPolicy string
+
+	// MaxReschedules specifies how many times a volume which failed
+	// to be provisioned is rescheduled, preferably on another node.
+	MaxReschedules int
+
+	// SpreadBy specifies how the volumes spread across the topology
+	// domains are grouped, by StatefulSet or by a PVC label, empty if
+	// the volumes are not spread.
+	SpreadBy string
+
+	// SpreadLabel specifies the PVC label grouping the volumes
+	// to spread when they are spread by label.
+	SpreadLabel string
+
+	// SpreadTopologyKey specifies the node label whose values are
+	// the topology domains the volumes are spread across.
+	SpreadTopologyKey string
+
+	// SpreadPolicy specifies whether the volumes of a group are
+	// preferably or necessarily provisioned in different domains.
+	SpreadPolicy string
+
+	// SpreadGroup is the hash of the group of volumes the volume is
+	// spread from, empty if the volume is not spread.
+	SpreadGroup string
+
+	// LVNameTemplate specifies the template of the logical volume
+	// names, rendered with the metadata of the pvc.
+	LVNameTemplate *template.Template
+
+	// LVName is the name of the logical volume rendered from the
+	// LVNameTemplate, empty if the storage class has no template.
+	LVName string
+
+	// Modify holds the mutable parameters of the volume, set
+	// in the volume attributes class of its PVC.
+	Modify ModifyParams
+
 	// extra optional metadata passed by external provisioner
 	// if enabled. See --extra-create-metadata flag for more details.
 	// https://github.com/kubernetes-csi/external-provisioner#recommended-optional-arguments
@@ -44,6 +112,23 @@ type VolumeParams struct {
 	PVName       string
 }
 
+// ModifyParams holds the mutable parameters of a volume which can be
+// configured in a volume attributes class, and modified after the
+// volume has been provisioned.
+type ModifyParams struct {
+	// Permission specifies the access permission of
+	// the logical volume, rw or r.
+	Permission string
+
+	// ErrorWhenFull specifies whether the thin pool of the volume
+	// fails the IOs immediately when it is full, yes or no.
+	ErrorWhenFull string
+
+	// IOLimits specifies the IO limits of the volume, nil if
+	// the class does not set any.
+	IOLimits *lvmapi.VolumeIOLimits
+}
+
 // SnapshotParams holds collection of supported settings that can
 // be configured in snapshot class.
 type SnapshotParams struct {
@@ -54,9 +139,14 @@ type SnapshotParams struct {
 // NewVolumeParams parses the input params and instantiates new VolumeParams.
 func NewVolumeParams(m map[string]string) (*VolumeParams, error) {
 	params := &VolumeParams{ // set up defaults, if any.
-		Scheduler:     SpaceWeighted,
-		Shared:        "no",
-		ThinProvision: "no",
+		Shared:                "no",
+		ThinProvision:         "no",
+		OverProvisioningRatio: 1,
+		ThinPoolThreshold:     100,
+		DeletionPolicy:        lvm.DeletionPolicyBlock,
+		MaxReschedules:        3,
+		SpreadTopologyKey:     DefaultSpreadTopologyKey,
+		SpreadPolicy:          SpreadPolicyPreferred,
 	}
 	// parameter keys may be mistyped from the CRD specification when declaring
 	// the storageclass, which kubectl validation will not catch. Because
@@ -80,9 +170,13 @@ func NewVolumeParams(m map[string]string) (*VolumeParams, error) {
 
 	// parse string params
 	stringParams := map[string]*string{
-		"scheduler":     &params.Scheduler,
-		"shared":        &params.Shared,
-		"thinprovision": &params.ThinProvision,
+		"shared":            &params.Shared,
+		"thinprovision":     &params.ThinProvision,
+		"deletionpolicy":    &params.DeletionPolicy,
+		"spreadby":          &params.SpreadBy,
+		"spreadlabel":       &params.SpreadLabel,
+		"spreadtopologykey": &params.SpreadTopologyKey,
+		"spreadpolicy":      &params.SpreadPolicy,
 	}
 	for key, param := range stringParams {
 		value, ok := m[key]
@@ -92,6 +186,58 @@ func NewVolumeParams(m map[string]string) (*VolumeParams, error) {
 		*param = value
 	}
 
+	// the space weighted scheduler is the default one
+	scheduler := SpaceWeighted
+	if value, ok := m["scheduler"]; ok {
+		scheduler = value
+	}
+	if params.Scheduler, err = parseScheduler(scheduler); err != nil {
+		return nil, fmt.Errorf("invalid scheduler param %v: %v", scheduler, err)
+	}
+
+	if ratio, ok := m["overprovisioningratio"]; ok {
+		if params.OverProvisioningRatio, err = strconv.ParseFloat(ratio, 64); err != nil {
+			return nil, fmt.Errorf("invalid overProvisioningRatio param %v: %v", ratio, err)
+		}
+		if params.OverProvisioningRatio <= 0 {
+			return nil, fmt.Errorf("overProvisioningRatio should be greater than 0, found %v", ratio)
+		}
+	}
+
+	if threshold, ok := m["thinpoolthreshold"]; ok {
+		if params.ThinPoolThreshold, err = strconv.Atoi(strings.TrimSuffix(threshold, "%")); err != nil {
+			return nil, fmt.Errorf("invalid thinPoolThreshold param %v: %v", threshold, err)
+		}
+		if params.ThinPoolThreshold < 1 || params.ThinPoolThreshold > 100 {
+			return nil, fmt.Errorf("thinPoolThreshold should be between 1 and 100, found %v", threshold)
+		}
+	}
+
+	if reschedules, ok := m["maxreschedules"]; ok {
+		if params.MaxReschedules, err = strconv.Atoi(reschedules); err != nil {
+			return nil, fmt.Errorf("invalid maxReschedules param %v: %v", reschedules, err)
+		}
+		if params.MaxReschedules < 0 {
+			return nil, fmt.Errorf("maxReschedules should not be negative, found %v", reschedules)
+		}
+	}
+
+	if params.DeletionPolicy != lvm.DeletionPolicyBlock &&
+		params.DeletionPolicy != lvm.DeletionPolicyCascade {
+		return nil, fmt.Errorf("invalid deletionPolicy param %v, should be %s or %s",
+			params.DeletionPolicy, lvm.DeletionPolicyBlock, lvm.DeletionPolicyCascade)
+	}
+
+	if err = validateSpreadParams(params); err != nil {
+		return nil, err
+	}
+
+	if text, ok := m["lvnametemplate"]; ok {
+		if params.LVNameTemplate, err = parseLVNameTemplate(text); err != nil {
+			return nil, fmt.Errorf("invalid lvNameTemplate param %v: %v", text, err)
+		}
+	}
+
 	params.PVCName = m["csi.storage.k8s.io/pvc/name"]
 	params.PVCNamespace = m["csi.storage.k8s.io/pvc/namespace"]
 	params.PVName = m["csi.storage.k8s.io/pv/name"]
@@ -99,6 +245,104 @@ func NewVolumeParams(m map[string]string) (*VolumeParams, error) {
 	return params, nil
 }
 
+// pvcAnnotations returns the annotations of the LVMVolume recording
+// its PVC, used by the node agent to record the events on the PVC.
+func (p *VolumeParams) pvcAnnotations() map[string]string {
+	if p.PVCName == "" || p.PVCNamespace == "" {
+		return nil
+	}
+	return map[string]string{
+		lvm.LVMPVCNameKey:      p.PVCName,
+		lvm.LVMPVCNamespaceKey: p.PVCNamespace,
+	}
+}
+
+// allowsPVCOverrides returns true if the storage class parameters
+// allow the PVC annotations to override some of them.
+func allowsPVCOverrides(m map[string]string) bool {
+	return strings.Trim(helpers.GetCaseInsensitiveMap(&m)[AllowedOverridesKey], " ,") != ""
+}
+
+// mergePVCOverrides returns the storage class parameters merged with the
+// overrides set in the PVC annotations having the PVCOverridePrefix. Only
+// the parameters listed in the allowedOverrides parameter of the storage
+// class can be overridden.
+func mergePVCOverrides(m map[string]string, annotations map[string]string) (map[string]string, error) {
+	merged := helpers.GetCaseInsensitiveMap(&m)
+
+	allowed := map[string]bool{}
+	for _, key := range strings.Split(merged[AllowedOverridesKey], ",") {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			allowed[key] = true
+		}
+	}
+
+	overridden := map[string]bool{}
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, PVCOverridePrefix) {
+			continue
+		}
+		param := strings.ToLower(strings.TrimPrefix(key, PVCOverridePrefix))
+		if !allowed[param] || param == AllowedOverridesKey ||
+			strings.HasPrefix(param, "csi.storage.k8s.io/") {
+			return nil, fmt.Errorf("parameter %s set by the annotation %s "+
+				"is not allowed to be overridden by the storageclass", param, key)
+		}
+		merged[param] = value
+		overridden[param] = true
+	}
+
+	// volgroup takes precedence over vgpattern, so drop the volgroup
+	// of the storage class when the pvc overrides the vgpattern.
+	if overridden["vgpattern"] && !overridden["volgroup"] {
+		delete(merged, "volgroup")
+	}
+	return merged, nil
+}
+
+// NewModifyParams parses the mutable parameters of a volume. The
+// parameters are the whole set of the volume attributes class, so the
+// IO limits which are not set are removed from the volume.
+func NewModifyParams(m map[string]string) (*ModifyParams, error) {
+	params := &ModifyParams{}
+	m = helpers.GetCaseInsensitiveMap(&m)
+
+	var limits lvmapi.VolumeIOLimits
+	limitParams := map[string]*uint64{
+		"readiops":  &limits.ReadIOPS,
+		"writeiops": &limits.WriteIOPS,
+		"readbps":   &limits.ReadBPS,
+		"writebps":  &limits.WriteBPS,
+	}
+	for key, value := range m {
+		switch key {
+		case "permission":
+			if value != "rw" && value != "r" {
+				return nil, fmt.Errorf("invalid permission param %v, should be rw or r", value)
+			}
+			params.Permission = value
+		case "errorwhenfull":
+			if value != lvm.YES && value != "no" {
+				return nil, fmt.Errorf("invalid errorWhenFull param %v, should be yes or no", value)
+			}
+			params.ErrorWhenFull = value
+		default:
+			limit, ok := limitParams[key]
+			if !ok {
+				return nil, fmt.Errorf("parameter %s can not be modified", key)
+			}
+			var err error
+			if *limit, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s param %v: %v", key, value, err)
+			}
+			if params.IOLimits == nil {
+				params.IOLimits = &limits
+			}
+		}
+	}
+	return params, nil
+}
+
 // NewSnapshotParams parses the input params and instantiates new SnapshotParams.
 func NewSnapshotParams(m map[string]string) (*SnapshotParams, error) {
 	var err error
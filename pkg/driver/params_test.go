@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+)
+
+func Test_mergePVCOverrides(t *testing.T) {
+	scParams := map[string]string{
+		"volgroup":         "lvmvg",
+		"thinProvision":    "no",
+		"allowedOverrides": "vgPattern, thinprovision",
+	}
+	tests := map[string]struct {
+		annotations map[string]string
+		want        map[string]string
+		wantErr     bool
+	}{
+		"no annotations": {
+			want: map[string]string{
+				"volgroup":         "lvmvg",
+				"thinprovision":    "no",
+				"allowedoverrides": "vgPattern, thinprovision",
+			},
+		},
+		"allowed overrides": {
+			annotations: map[string]string{
+				PVCOverridePrefix + "thinProvision": "yes",
+				PVCOverridePrefix + "vgpattern":     "^ssd-.*$",
+				"unrelated.io/thinprovision":        "no",
+			},
+			want: map[string]string{
+				"vgpattern":        "^ssd-.*$",
+				"thinprovision":    "yes",
+				"allowedoverrides": "vgPattern, thinprovision",
+			},
+		},
+		"disallowed override": {
+			annotations: map[string]string{PVCOverridePrefix + "shared": "yes"},
+			wantErr:     true,
+		},
+		"allowlist override": {
+			annotations: map[string]string{PVCOverridePrefix + "allowedOverrides": "shared"},
+			wantErr:     true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := mergePVCOverrides(scParams, tt.annotations)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewModifyParams(t *testing.T) {
+	tests := map[string]struct {
+		params  map[string]string
+		want    *ModifyParams
+		wantErr bool
+	}{
+		"none": {want: &ModifyParams{}},
+		"permission and when full": {
+			params: map[string]string{"permission": "r", "errorWhenFull": "yes"},
+			want:   &ModifyParams{Permission: "r", ErrorWhenFull: "yes"},
+		},
+		"io limits": {
+			params: map[string]string{"readIOPS": "500", "writeBPS": "1048576"},
+			want: &ModifyParams{
+				IOLimits: &lvmapi.VolumeIOLimits{ReadIOPS: 500, WriteBPS: 1048576},
+			},
+		},
+		"invalid permission": {params: map[string]string{"permission": "w"}, wantErr: true},
+		"invalid when full":  {params: map[string]string{"errorWhenFull": "true"}, wantErr: true},
+		"invalid limit":      {params: map[string]string{"readIOPS": "-1"}, wantErr: true},
+		"immutable":          {params: map[string]string{"thinProvision": "yes"}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewModifyParams(tt.params)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// reservation is the capacity reserved on a node for a volume
+// scheduled with the given volume group pattern.
+type reservation struct {
+	node      string
+	vgPattern *regexp.Regexp
+	// volGroup is the volume group the volume is placed in,
+	// when it is known before the node agent provisions the volume.
+	volGroup string
+	// group is the group the volume is spread from.
+	group string
+	size  int64
+}
+
+// inVolumeGroup returns true if the capacity of the reservation
+// is or may be allocated from the volume group.
+func (r *reservation) inVolumeGroup(vgName string) bool {
+	if r.volGroup != "" {
+		return r.volGroup == vgName
+	}
+	return r.vgPattern != nil && r.vgPattern.MatchString(vgName)
+}
+
+// reservationLedger keeps track of the capacity reserved for the volumes
+// which have been scheduled on a node but are not yet provisioned by the
+// node agent. The free space reported by the LVMNode resources does not
+// account for such volumes, so without the reservations the concurrent
+// CreateVolume requests would all pick the same node. The reservations are
+// resolved against the volume groups of the nodes, so that the volumes
+// scheduled with different patterns matching the same volume groups see
+// each other's reservations.
+type reservationLedger struct {
+	// schedMu serializes the node selection and the reservation of the
+	// capacity, so that each request sees the reservations of the others.
+	schedMu sync.Mutex
+
+	mu sync.Mutex
+	// reservations has the reservation of each volume.
+	reservations map[string]*reservation
+}
+
+// capacityReservations is the reservation ledger of the controller.
+var capacityReservations = newReservationLedger()
+
+func newReservationLedger() *reservationLedger {
+	return &reservationLedger{
+		reservations: map[string]*reservation{},
+	}
+}
+
+// reserve reserves the capacity for the volume on the given node,
+// replacing the previous reservation of the volume if any. The spread
+// group of the volume, if any, is kept along with the reservation.
+func (l *reservationLedger) reserve(volName, node, vgPattern, group string, size int64) {
+	re, err := regexp.Compile(vgPattern)
+	if err != nil {
+		klog.Warningf("invalid volume group pattern %q of the volume %s: %v",
+			vgPattern, volName, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.reservations[volName] = &reservation{
+		node:      node,
+		vgPattern: re,
+		group:     group,
+		size:      size,
+	}
+}
+
+// release drops the reservation of the volume if any.
+func (l *reservationLedger) release(volName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.reservations, volName)
+}
+
+// assignVolumeGroup records the volume group the reserved
+// capacity of the volume is going to be allocated from.
+func (l *reservationLedger) assignVolumeGroup(volName, vgName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r, ok := l.reservations[volName]; ok && vgName != "" {
+		r.volGroup = vgName
+	}
+}
+
+// reserved returns the capacity reserved on the node for the volumes
+// which are or may be allocated from the volume groups of the node
+// matching the regular expression, leaving out the volumes present
+// in the exclude set.
+func (l *reservationLedger) reserved(node *lvmapi.LVMNode, re *regexp.Regexp,
+	exclude map[string]bool) int64 {
+	nodeID := lvm.GetLVMNodeID(node)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var size int64
+	for volName, r := range l.reservations {
+		if r.node != nodeID || exclude[volName] {
+			continue
+		}
+		for i := range node.VolumeGroups {
+			vgName := node.VolumeGroups[i].Name
+			if re.MatchString(vgName) && r.inVolumeGroup(vgName) {
+				size += r.size
+				break
+			}
+		}
+	}
+	return size
+}
+
+// volumeGroupReserved returns the capacity reserved in each volume group of
+// the node matching the regular expression, leaving out the volumes present
+// in the exclude set. The capacity of the volumes whose volume group is yet
+// to be picked by the node agent is counted in all the volume groups they
+// may be allocated from.
+func (l *reservationLedger) volumeGroupReserved(node *lvmapi.LVMNode, re *regexp.Regexp,
+	exclude map[string]bool) map[string]int64 {
+	nodeID := lvm.GetLVMNodeID(node)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	vgmap := map[string]int64{}
+	for i := range node.VolumeGroups {
+		vgName := node.VolumeGroups[i].Name
+		if !re.MatchString(vgName) {
+			continue
+		}
+		for volName, r := range l.reservations {
+			if r.node == nodeID && !exclude[volName] && r.inVolumeGroup(vgName) {
+				vgmap[vgName] += r.size
+			}
+		}
+	}
+	return vgmap
+}
+
+// groupNodes returns the nodes of the volumes of the
+// spread group having a reservation, keyed by volume.
+func (l *reservationLedger) groupNodes(group string) map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	nodes := map[string]string{}
+	for volName, r := range l.reservations {
+		if r.group == group {
+			nodes[volName] = r.node
+		}
+	}
+	return nodes
+}
+
+// syncVolume reserves the capacity for the volume while it is pending
+// and releases it once the node agent has marked the volume as ready
+// or failed, or the volume has been deleted.
+func (l *reservationLedger) syncVolume(vol *lvmapi.LVMVolume) {
+	if vol.Status.State != lvm.LVMStatusPending || vol.DeletionTimestamp != nil {
+		l.release(vol.Name)
+		return
+	}
+	size, err := strconv.ParseInt(vol.Spec.Capacity, 10, 64)
+	if err != nil {
+		klog.Warningf("invalid capacity %q of the volume %s: %v",
+			vol.Spec.Capacity, vol.Name, err)
+		return
+	}
+	l.reserve(vol.Name, vol.Spec.OwnerNodeID, vol.Spec.VgPattern,
+		vol.Labels[lvm.LVMSpreadGroupKey], size)
+	l.assignVolumeGroup(vol.Name, vol.Spec.VolGroup)
+}
+
+// volumeEventHandler returns the LVMVolume informer event handler
+// keeping the reservations in sync with the volumes.
+func (l *reservationLedger) volumeEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if vol, ok := obj.(*lvmapi.LVMVolume); ok {
+				l.syncVolume(vol)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if vol, ok := newObj.(*lvmapi.LVMVolume); ok {
+				l.syncVolume(vol)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombStone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombStone.Obj
+			}
+			if vol, ok := obj.(*lvmapi.LVMVolume); ok {
+				l.release(vol.Name)
+			}
+		},
+	}
+}
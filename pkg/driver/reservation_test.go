@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// testReservationNode returns an LVMNode having the volume groups.
+func testReservationNode(name string, vgs ...string) *lvmapi.LVMNode {
+	node := &lvmapi.LVMNode{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	for _, vg := range vgs {
+		node.VolumeGroups = append(node.VolumeGroups, lvmapi.VolumeGroup{Name: vg})
+	}
+	return node
+}
+
+func TestReservationLedger(t *testing.T) {
+	l := newReservationLedger()
+	node1 := testReservationNode("node-1", "lvmvg", "other")
+	node2 := testReservationNode("node-2", "lvmvg", "other")
+	lvmvg := regexp.MustCompile("^lvmvg$")
+	other := regexp.MustCompile("^other$")
+
+	l.reserve("pvc-1", "node-1", "^lvmvg$", "", 10*Gi)
+	l.reserve("pvc-2", "node-1", "^lvmvg$", "", 5*Gi)
+	l.reserve("pvc-3", "node-2", "^lvmvg$", "group", 1*Gi)
+	l.reserve("pvc-4", "node-1", "^other$", "group", 100*Gi)
+	assert.Equal(t, int64(15*Gi), l.reserved(node1, lvmvg, nil))
+	assert.Equal(t, int64(1*Gi), l.reserved(node2, lvmvg, nil))
+	assert.Equal(t, int64(10*Gi), l.reserved(node1, lvmvg, map[string]bool{"pvc-2": true}))
+
+	// rescheduling the volume moves its reservation to the new node
+	l.reserve("pvc-1", "node-2", "^lvmvg$", "", 10*Gi)
+	assert.Equal(t, int64(5*Gi), l.reserved(node1, lvmvg, nil))
+	assert.Equal(t, int64(11*Gi), l.reserved(node2, lvmvg, nil))
+
+	assert.Equal(t, map[string]string{"pvc-3": "node-2", "pvc-4": "node-1"}, l.groupNodes("group"))
+
+	// the reservations are counted against the volume group of the volume once it is known
+	l.assignVolumeGroup("pvc-1", "lvmvg")
+	l.assignVolumeGroup("unknown", "lvmvg")
+	assert.Equal(t, map[string]int64{"lvmvg": 11 * Gi},
+		l.volumeGroupReserved(node2, lvmvg, nil))
+	assert.Equal(t, map[string]int64{"lvmvg": 1 * Gi},
+		l.volumeGroupReserved(node2, lvmvg, map[string]bool{"pvc-1": true}))
+
+	l.release("pvc-1")
+	l.release("pvc-2")
+	l.release("unknown")
+	l.release("pvc-4")
+	assert.Equal(t, map[string]string{"pvc-3": "node-2"}, l.groupNodes("group"))
+	l.reserve("pvc-4", "node-1", "^other$", "", 100*Gi)
+	assert.Equal(t, int64(0), l.reserved(node1, lvmvg, nil))
+	assert.Equal(t, int64(1*Gi), l.reserved(node2, lvmvg, nil))
+	assert.Equal(t, int64(100*Gi), l.reserved(node1, other, nil))
+}
+
+func TestReservationLedgerOverlappingPatterns(t *testing.T) {
+	l := newReservationLedger()
+	node := testReservationNode("node-1", "vg1", "vg2", "other")
+
+	// the volume scheduled with vg.* may be allocated from vg1 or vg2
+	l.reserve("pvc-1", "node-1", "vg.*", "", 10*Gi)
+	l.reserve("pvc-2", "node-1", "^vg1$", "", 5*Gi)
+	l.reserve("pvc-3", "node-1", "vg.*", "", 1*Gi)
+	l.assignVolumeGroup("pvc-3", "vg2")
+
+	assert.Equal(t, map[string]int64{"vg1": 15 * Gi},
+		l.volumeGroupReserved(node, regexp.MustCompile("^vg1$"), nil))
+	assert.Equal(t, map[string]int64{"vg1": 15 * Gi, "vg2": 11 * Gi},
+		l.volumeGroupReserved(node, regexp.MustCompile("vg.*"), nil))
+	assert.Equal(t, map[string]int64{},
+		l.volumeGroupReserved(node, regexp.MustCompile("^other$"), nil))
+
+	assert.Equal(t, int64(15*Gi), l.reserved(node, regexp.MustCompile("^vg1$"), nil))
+	assert.Equal(t, int64(11*Gi), l.reserved(node, regexp.MustCompile("^vg2$"), nil))
+	assert.Equal(t, int64(16*Gi), l.reserved(node, regexp.MustCompile("vg.*"), nil))
+	assert.Equal(t, int64(0), l.reserved(node, regexp.MustCompile("^other$"), nil))
+}
+
+func TestReservationLedgerSyncVolume(t *testing.T) {
+	l := newReservationLedger()
+	vol := &lvmapi.LVMVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec: lvmapi.VolumeInfo{
+			OwnerNodeID: "node-1",
+			VgPattern:   "lvmvg",
+			Capacity:    "1073741824",
+		},
+		Status: lvmapi.VolStatus{State: lvm.LVMStatusPending},
+	}
+
+	node := testReservationNode("node-1", "lvmvg", "lvmvg-1")
+	lvmvg := regexp.MustCompile("lvmvg")
+	l.syncVolume(vol)
+	assert.Equal(t, int64(Gi), l.reserved(node, lvmvg, nil))
+	assert.Equal(t, map[string]int64{"lvmvg": Gi, "lvmvg-1": Gi}, l.volumeGroupReserved(node, lvmvg, nil))
+
+	placed := vol.DeepCopy()
+	placed.Spec.VolGroup = "lvmvg-1"
+	l.syncVolume(placed)
+	assert.Equal(t, map[string]int64{"lvmvg-1": Gi}, l.volumeGroupReserved(node, lvmvg, nil))
+
+	for _, state := range []string{lvm.LVMStatusReady, lvm.LVMStatusFailed} {
+		l.syncVolume(vol)
+		processed := vol.DeepCopy()
+		processed.Status.State = state
+		l.syncVolume(processed)
+		assert.Zero(t, l.reserved(node, lvmvg, nil), state)
+	}
+
+	l.syncVolume(vol)
+	deleted := vol.DeepCopy()
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	l.syncVolume(deleted)
+	assert.Zero(t, l.reserved(node, lvmvg, nil))
+}
@@ -19,12 +19,13 @@ package driver
 import (
 	"math"
 	"regexp"
+	"sort"
 	"strconv"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
 
-	"github.com/openebs/lvm-localpv/pkg/builder/nodebuilder"
-	"github.com/openebs/lvm-localpv/pkg/builder/volbuilder"
 	"github.com/openebs/lvm-localpv/pkg/lvm"
 )
 
@@ -39,6 +40,10 @@ const (
 	// pick the node which is less loaded space wise
 	// this will be the default scheduler when none provided
 	SpaceWeighted = "SpaceWeighted"
+
+	// pick the node and the volume group with the least free space
+	// which still fits the volume, to pack the volumes on fewer nodes
+	BinPacking = "BinPacking"
 )
 
 // getVolumeWeightedMap goes through all the volumegroup on all the nodes
@@ -48,20 +53,15 @@ const (
 func getVolumeWeightedMap(re *regexp.Regexp) (map[string]int64, error) {
 	nmap := map[string]int64{}
 
-	vollist, err := volbuilder.NewKubeclient().
-		WithNamespace(lvm.LvmNamespace).
-		List(metav1.ListOptions{})
-
+	vols, err := schedLister.lvmVolumes(re)
 	if err != nil {
 		return nmap, err
 	}
 
 	// create the map of the volume count
 	// for the given vg
-	for _, vol := range vollist.Items {
-		if re.MatchString(vol.Spec.VolGroup) {
-			nmap[vol.Spec.OwnerNodeID]++
-		}
+	for _, vol := range vols {
+		nmap[vol.Spec.OwnerNodeID]++
 	}
 
 	return nmap, nil
@@ -71,26 +71,37 @@ func getVolumeWeightedMap(re *regexp.Regexp) (map[string]int64, error) {
 // and creates the node mapping of the capacity for all the nodes.
 // It returns a map which has nodes as key and capacity provisioned
 // on the nodes as corresponding value. The scheduler will use this map
-// and picks the node which is less weighted.
+// and picks the node which is less weighted. The capacity reserved for
+// the volumes which are yet to be created is also added to the map.
 func getCapacityWeightedMap(re *regexp.Regexp) (map[string]int64, error) {
 	nmap := map[string]int64{}
 
-	volList, err := volbuilder.NewKubeclient().
-		WithNamespace(lvm.LvmNamespace).
-		List(metav1.ListOptions{})
-
+	vols, err := schedLister.lvmVolumes(re)
 	if err != nil {
 		return nmap, err
 	}
 
 	// create the map of the volume capacity
 	// for the given volume group
-	for _, vol := range volList.Items {
-		if re.MatchString(vol.Spec.VolGroup) {
-			volSize, err := strconv.ParseInt(vol.Spec.Capacity, 10, 64)
-			if err == nil {
-				nmap[vol.Spec.OwnerNodeID] += volSize
-			}
+	counted := map[string]bool{}
+	for _, vol := range vols {
+		volSize, err := strconv.ParseInt(vol.Spec.Capacity, 10, 64)
+		if err == nil {
+			nmap[vol.Spec.OwnerNodeID] += volSize
+			counted[vol.Name] = true
+		}
+	}
+
+	nodes, err := schedLister.lvmNodes()
+	if err != nil {
+		return nmap, err
+	}
+
+	// the volumes which already have their volume group set
+	// are accounted for above
+	for _, node := range nodes {
+		if size := capacityReservations.reserved(node, re, counted); size > 0 {
+			nmap[lvm.GetLVMNodeID(node)] += size
 		}
 	}
 
@@ -99,48 +110,198 @@ func getCapacityWeightedMap(re *regexp.Regexp) (map[string]int64, error) {
 
 // getSpaceWeightedMap returns how weighted a node is space wise.
 // The node which has max free space available is less loaded and
-// can accumulate more volumes.
-func getSpaceWeightedMap(re *regexp.Regexp) (map[string]int64, error) {
+// can accumulate more volumes. The capacity reserved for the volumes
+// which are yet to be provisioned is subtracted from the free space.
+// For the thin provisioned volumes, the free space of the thin pools
+// is considered instead of the one of the volume groups.
+func getSpaceWeightedMap(params *VolumeParams) (map[string]int64, error) {
+	re := params.VgPattern
 	nmap := map[string]int64{}
 
-	nodeList, err := nodebuilder.NewKubeclient().
-		WithNamespace(lvm.LvmNamespace).
-		List(metav1.ListOptions{})
-
+	nodes, err := schedLister.lvmNodes()
 	if err != nil {
 		return nmap, err
 	}
 
-	for _, node := range nodeList.Items {
+	for _, node := range nodes {
+		reserved := capacityReservations.volumeGroupReserved(node, re, nil)
 		var maxFree int64 = 0
-		for _, vg := range node.VolumeGroups {
+		for i := range node.VolumeGroups {
+			vg := &node.VolumeGroups[i]
 			if re.MatchString(vg.Name) {
-				freeCapacity := vg.Free.Value()
+				freeCapacity := getVGFreeSpace(vg, params) - reserved[vg.Name]
 				if maxFree < freeCapacity {
 					maxFree = freeCapacity
 				}
 			}
 		}
-		if maxFree > 0 {
-			// converting to SpaceWeighted by subtracting it with MaxInt64
-			// as the node which has max free space available is less loaded.
-			nmap[node.Name] = math.MaxInt64 - maxFree
+		// converting to SpaceWeighted by subtracting it with MaxInt64
+		// as the node which has max free space available is less loaded.
+		// The nodes without free space get the max weight, so that they
+		// are not preferred as the nodes missing in the map.
+		nmap[lvm.GetLVMNodeID(node)] = math.MaxInt64 - maxFree
+	}
+
+	return nmap, nil
+}
+
+// bestFitVolumeGroup returns the volume group of the node having the least
+// capacity left once the volume is provisioned in it, along with the
+// capacity left. The capacity of the thin provisioned volumes is taken
+// from the thin pool headroom, including the space of the volume group
+// the pool can grow into, as per the overprovisioning ratio. The reserved
+// capacity is keyed by volume group.
+func bestFitVolumeGroup(node *lvmapi.LVMNode, params *VolumeParams,
+	reserved map[string]int64, capacity int64) (string, int64, bool) {
+	var (
+		bestVG   string
+		bestLeft int64
+		found    bool
+	)
+	for i := range node.VolumeGroups {
+		vg := &node.VolumeGroups[i]
+		if !params.VgPattern.MatchString(vg.Name) {
+			continue
+		}
+		left := getVGCapacity(vg, params) - reserved[vg.Name] - capacity
+		if left < 0 {
+			continue
+		}
+		if !found || left < bestLeft {
+			bestVG, bestLeft, found = vg.Name, left, true
+		}
+	}
+	return bestVG, bestLeft, found
+}
+
+// binPackingScheduler picks the node and the volume group where the
+// volume fits with the least capacity left, so that the volumes are
+// packed on fewer nodes and the empty nodes can be drained.
+type binPackingScheduler struct{}
+
+// NodeWeights returns the capacity left on the best fitting volume group
+// of the nodes once the volume is provisioned. The nodes where the volume
+// does not fit get the max weight, so that they are picked last.
+func (binPackingScheduler) NodeWeights(params *VolumeParams, capacity int64) (map[string]int64, error) {
+	nmap := map[string]int64{}
+
+	nodes, err := schedLister.lvmNodes()
+	if err != nil {
+		return nmap, err
+	}
+
+	for _, node := range nodes {
+		nodeID := lvm.GetLVMNodeID(node)
+		reserved := capacityReservations.volumeGroupReserved(node, params.VgPattern, nil)
+		_, left, ok := bestFitVolumeGroup(node, params, reserved, capacity)
+		if !ok {
+			left = math.MaxInt64
 		}
+		nmap[nodeID] = left
 	}
 
 	return nmap, nil
 }
 
-// getNodeMap returns the node mapping for the given scheduling algorithm
-func getNodeMap(schd string, vgPattern *regexp.Regexp) (map[string]int64, error) {
-	switch schd {
-	case VolumeWeighted:
-		return getVolumeWeightedMap(vgPattern)
-	case CapacityWeighted:
-		return getCapacityWeightedMap(vgPattern)
-	case SpaceWeighted:
-		return getSpaceWeightedMap(vgPattern)
-	}
-	// return getSpaceWeightedMap(default) if not specified
-	return getSpaceWeightedMap(vgPattern)
+// VolumeGroup returns the best fitting volume group of the node,
+// or an empty name if the volume does not fit in any of them.
+func (binPackingScheduler) VolumeGroup(nodeID, volName string,
+	params *VolumeParams, capacity int64) (string, error) {
+	node, err := schedLister.lvmNode(nodeID)
+	if err != nil || node == nil {
+		return "", err
+	}
+	// the reservation of the volume itself is already
+	// made on the node, so it is not counted again.
+	reserved := capacityReservations.volumeGroupReserved(node, params.VgPattern,
+		map[string]bool{volName: true})
+	vgName, _, _ := bestFitVolumeGroup(node, params, reserved, capacity)
+	return vgName, nil
+}
+
+// getThinPoolFullNodes returns the nodes where the thin volumes can not be
+// provisioned, as the thin pools of all their volume groups matching the
+// volume reached the usage threshold.
+func getThinPoolFullNodes(params *VolumeParams) (map[string]bool, error) {
+	full := map[string]bool{}
+
+	nodes, err := schedLister.lvmNodes()
+	if err != nil {
+		return full, err
+	}
+
+	for _, node := range nodes {
+		matched, available := false, false
+		for j := range node.VolumeGroups {
+			vg := &node.VolumeGroups[j]
+			if !params.VgPattern.MatchString(vg.Name) {
+				continue
+			}
+			matched = true
+			if pool := lvm.GetThinPool(vg); pool == nil || !thinPoolFull(pool, params) {
+				available = true
+				break
+			}
+		}
+		if matched && !available {
+			full[lvm.GetLVMNodeID(node)] = true
+		}
+	}
+
+	return full, nil
+}
+
+// selectNodes returns the nodes satisfying the topology requirements
+// of the volume, ordered by their weights. The nodes missing in the
+// weights come first, as they do not host any volume matching it.
+func selectNodes(req *csi.CreateVolumeRequest, nmap map[string]int64) ([]string, error) {
+	areq := req.GetAccessibilityRequirements()
+	topo := areq.GetPreferred()
+	if len(topo) == 0 {
+		// if preferred list is empty, use the requisite
+		topo = areq.GetRequisite()
+	}
+	if len(topo) == 0 {
+		return nil, nil
+	}
+
+	k8sNodes, err := schedLister.k8sNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	var preferred, weighted []string
+	for _, node := range k8sNodes {
+		if !matchesTopology(node.Labels, topo) {
+			continue
+		}
+		if _, ok := nmap[node.Name]; ok {
+			weighted = append(weighted, node.Name)
+		} else {
+			preferred = append(preferred, node.Name)
+		}
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return nmap[weighted[i]] < nmap[weighted[j]]
+	})
+	return append(preferred, weighted...), nil
+}
+
+// matchesTopology returns true if the labels match
+// all the segments of any of the topologies.
+func matchesTopology(labels map[string]string, topo []*csi.Topology) bool {
+	for _, t := range topo {
+		matched := true
+		for key, value := range t.GetSegments() {
+			if labels[key] != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,198 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+)
+
+func Test_bestFitVolumeGroup(t *testing.T) {
+	node := &lvmapi.LVMNode{
+		VolumeGroups: []lvmapi.VolumeGroup{
+			{Name: "lvmvg-large", Free: *resource.NewQuantity(100*Gi, resource.BinarySI)},
+			{Name: "lvmvg-small", Free: *resource.NewQuantity(10*Gi, resource.BinarySI)},
+			{Name: "lvmvg-full", Free: *resource.NewQuantity(1*Gi, resource.BinarySI)},
+			{
+				Name: "lvmvg-thin",
+				Free: *resource.NewQuantity(0, resource.BinarySI),
+				ThinPools: []lvmapi.ThinPool{{
+					Name:        "lvmvg-thin_thinpool",
+					Size:        *resource.NewQuantity(50*Gi, resource.BinarySI),
+					Free:        *resource.NewQuantity(30*Gi, resource.BinarySI),
+					VirtualSize: *resource.NewQuantity(41*Gi, resource.BinarySI),
+				}},
+			},
+			{Name: "other", Free: *resource.NewQuantity(5*Gi, resource.BinarySI)},
+		},
+	}
+	tests := map[string]struct {
+		thin     string
+		ratio    float64
+		reserved map[string]int64
+		capacity int64
+		wantVG   string
+		wantLeft int64
+		wantOK   bool
+	}{
+		"tightest fit": {
+			capacity: 4 * Gi, wantVG: "lvmvg-small", wantLeft: 6 * Gi, wantOK: true,
+		},
+		"reserved capacity": {
+			reserved: map[string]int64{"lvmvg-small": 7 * Gi}, capacity: 4 * Gi,
+			wantVG: "lvmvg-large", wantLeft: 96 * Gi, wantOK: true,
+		},
+		"reserved capacity of other volume group": {
+			reserved: map[string]int64{"lvmvg-large": 95 * Gi}, capacity: 4 * Gi,
+			wantVG: "lvmvg-large", wantLeft: 1 * Gi, wantOK: true,
+		},
+		"does not fit": {
+			capacity: 200 * Gi,
+		},
+		"thin pool headroom": {
+			thin: "yes", ratio: 1, capacity: 8 * Gi, wantVG: "lvmvg-thin", wantLeft: 1 * Gi, wantOK: true,
+		},
+		"thin pool overprovisioned": {
+			thin: "yes", ratio: 2, capacity: 8 * Gi, wantVG: "lvmvg-small", wantLeft: 12 * Gi, wantOK: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			params := &VolumeParams{
+				VgPattern:             regexp.MustCompile("^lvmvg"),
+				ThinProvision:         tt.thin,
+				OverProvisioningRatio: tt.ratio,
+				ThinPoolThreshold:     100,
+			}
+			vg, left, ok := bestFitVolumeGroup(node, params, tt.reserved, tt.capacity)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantVG, vg)
+			if ok {
+				assert.Equal(t, tt.wantLeft, left)
+			}
+		})
+	}
+
+	// the thin pool created with the size of its first volume
+	// grows into the free space of its volume group
+	exact := &lvmapi.LVMNode{
+		VolumeGroups: []lvmapi.VolumeGroup{{
+			Name: "lvmvg",
+			Free: *resource.NewQuantity(20*Gi, resource.BinarySI),
+			ThinPools: []lvmapi.ThinPool{{
+				Name:        "lvmvg_thinpool",
+				Size:        *resource.NewQuantity(10*Gi, resource.BinarySI),
+				Free:        *resource.NewQuantity(10*Gi, resource.BinarySI),
+				VirtualSize: *resource.NewQuantity(10*Gi, resource.BinarySI),
+			}},
+		}},
+	}
+	params := &VolumeParams{
+		VgPattern:             regexp.MustCompile("^lvmvg$"),
+		ThinProvision:         "yes",
+		OverProvisioningRatio: 1,
+		ThinPoolThreshold:     100,
+	}
+	vg, left, ok := bestFitVolumeGroup(exact, params, nil, 8*Gi)
+	assert.True(t, ok)
+	assert.Equal(t, "lvmvg", vg)
+	assert.Equal(t, int64(12*Gi), left)
+}
+
+func Test_getSpaceWeightedMap(t *testing.T) {
+	nodes, _ := testSchedulingObjects(3, 0)
+	// node-2 has its free space fully reserved
+	nodes[2].VolumeGroups[0].Free = *resource.NewQuantity(Gi, resource.BinarySI)
+	il := newTestInformerLister(t, nodes, nil)
+	// node-3 has no LVMNode, so no volume can be provisioned on it
+	assert.NoError(t, il.k8sNodeIndexer.Add(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-3",
+			Labels: map[string]string{"kubernetes.io/hostname": "node-3"},
+		},
+	}))
+	defer withSchedLister(il)()
+
+	params := &VolumeParams{
+		VgPattern:             regexp.MustCompile("^lvmvg$"),
+		OverProvisioningRatio: 1,
+		ThinPoolThreshold:     100,
+	}
+	// the volume is reserved with another pattern matching the volume group
+	capacityReservations.reserve("pvc-reserved", "node-2", "^lvm", "", 2*Gi)
+	defer capacityReservations.release("pvc-reserved")
+
+	nmap, err := getSpaceWeightedMap(params)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		"node-0": math.MaxInt64 - Gi,
+		"node-1": math.MaxInt64 - 2*Gi,
+		"node-2": math.MaxInt64,
+	}, nmap)
+
+	// the nodes without free space come after the others,
+	// and are not preferred as the nodes missing in the map
+	var topo []*csi.Topology
+	for _, node := range []string{"node-0", "node-1", "node-2", "node-3"} {
+		topo = append(topo, &csi.Topology{
+			Segments: map[string]string{"kubernetes.io/hostname": node},
+		})
+	}
+	selected, err := selectNodes(&csi.CreateVolumeRequest{
+		AccessibilityRequirements: &csi.TopologyRequirement{Preferred: topo},
+	}, nmap)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-3", "node-1", "node-0", "node-2"}, selected)
+}
+
+func Test_getSpaceWeightedMapThinPool(t *testing.T) {
+	nodes, _ := testSchedulingObjects(2, 0)
+	// the thin pool of node-1 is sized to its first volume
+	nodes[1].VolumeGroups[0] = lvmapi.VolumeGroup{
+		Name: "lvmvg",
+		Free: *resource.NewQuantity(4*Gi, resource.BinarySI),
+		ThinPools: []lvmapi.ThinPool{{
+			Name:        "lvmvg_thinpool",
+			Size:        *resource.NewQuantity(2*Gi, resource.BinarySI),
+			Free:        *resource.NewQuantity(2*Gi, resource.BinarySI),
+			VirtualSize: *resource.NewQuantity(2*Gi, resource.BinarySI),
+		}},
+	}
+	defer withSchedLister(newTestInformerLister(t, nodes, nil))()
+
+	params := &VolumeParams{
+		VgPattern:             regexp.MustCompile("^lvmvg$"),
+		ThinProvision:         "yes",
+		OverProvisioningRatio: 1,
+		ThinPoolThreshold:     100,
+	}
+	nmap, err := getSpaceWeightedMap(params)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		"node-0": math.MaxInt64 - Gi,
+		"node-1": math.MaxInt64 - 4*Gi,
+	}, nmap)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"regexp"
+
+	k8sapi "github.com/openebs/lib-csi/pkg/client/k8s"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/builder/nodebuilder"
+	"github.com/openebs/lvm-localpv/pkg/builder/volbuilder"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// VolumeVolGroupIndex is the name of the index
+// of the LVMVolumes by their volume group.
+const VolumeVolGroupIndex = "volGroup"
+
+// schedulingLister lists the resources used for scheduling the volumes.
+// The objects returned are shared and must not be modified.
+type schedulingLister interface {
+	// k8sNodes returns all the kubernetes nodes.
+	k8sNodes() ([]*corev1.Node, error)
+	// lvmNodes returns all the LVMNodes of the driver.
+	lvmNodes() ([]*lvmapi.LVMNode, error)
+	// lvmNode returns the LVMNode of the given node, nil if it does not exist.
+	lvmNode(nodeID string) (*lvmapi.LVMNode, error)
+	// lvmVolumes returns the LVMVolumes of the driver whose
+	// volume group matches the regular expression.
+	lvmVolumes(re *regexp.Regexp) ([]*lvmapi.LVMVolume, error)
+	// groupVolumes returns the LVMVolumes of the driver
+	// spread from the given group.
+	groupVolumes(group string) ([]*lvmapi.LVMVolume, error)
+}
+
+// schedLister is the lister used by the schedulers. It lists the resources
+// from the API server, until the controller replaces it with the informer
+// backed one.
+var schedLister schedulingLister = apiLister{}
+
+// apiLister lists the resources from the API server on every call.
+type apiLister struct {
+	nodes   *nodebuilder.Kubeclient
+	volumes *volbuilder.Kubeclient
+}
+
+func (l apiLister) nodeClient() *nodebuilder.Kubeclient {
+	if l.nodes != nil {
+		return l.nodes
+	}
+	return nodebuilder.NewKubeclient().WithNamespace(lvm.LvmNamespace)
+}
+
+func (l apiLister) volumeClient() *volbuilder.Kubeclient {
+	if l.volumes != nil {
+		return l.volumes
+	}
+	return volbuilder.NewKubeclient().WithNamespace(lvm.LvmNamespace)
+}
+
+func (l apiLister) k8sNodes() ([]*corev1.Node, error) {
+	list, err := k8sapi.ListNodes(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*corev1.Node, 0, len(list.Items))
+	for i := range list.Items {
+		nodes = append(nodes, &list.Items[i])
+	}
+	return nodes, nil
+}
+
+func (l apiLister) lvmNodes() ([]*lvmapi.LVMNode, error) {
+	list, err := l.nodeClient().
+		List(metav1.ListOptions{LabelSelector: lvm.DriverLabelSelector()})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*lvmapi.LVMNode, 0, len(list.Items))
+	for i := range list.Items {
+		nodes = append(nodes, &list.Items[i])
+	}
+	return nodes, nil
+}
+
+func (l apiLister) lvmNode(nodeID string) (*lvmapi.LVMNode, error) {
+	node, err := l.nodeClient().Get(lvm.GetLVMNodeName(nodeID), metav1.GetOptions{})
+	if k8serror.IsNotFound(err) {
+		return nil, nil
+	}
+	return node, err
+}
+
+func (l apiLister) lvmVolumes(re *regexp.Regexp) ([]*lvmapi.LVMVolume, error) {
+	list, err := l.volumeClient().
+		List(metav1.ListOptions{LabelSelector: lvm.DriverLabelSelector()})
+	if err != nil {
+		return nil, err
+	}
+	var vols []*lvmapi.LVMVolume
+	for i := range list.Items {
+		if re.MatchString(list.Items[i].Spec.VolGroup) {
+			vols = append(vols, &list.Items[i])
+		}
+	}
+	return vols, nil
+}
+
+func (l apiLister) groupVolumes(group string) ([]*lvmapi.LVMVolume, error) {
+	list, err := l.volumeClient().List(metav1.ListOptions{
+		LabelSelector: lvm.DriverLabelSelector() + "," + lvm.LVMSpreadGroupKey + "=" + group,
+	})
+	if err != nil {
+		return nil, err
+	}
+	vols := make([]*lvmapi.LVMVolume, 0, len(list.Items))
+	for i := range list.Items {
+		vols = append(vols, &list.Items[i])
+	}
+	return vols, nil
+}
+
+// informerLister lists the resources from the caches of the shared
+// informers of the controller, which are already scoped to the driver.
+type informerLister struct {
+	k8sNodeIndexer   cache.Indexer
+	lvmNodeIndexer   cache.Indexer
+	lvmVolumeIndexer cache.Indexer
+}
+
+func (l informerLister) k8sNodes() ([]*corev1.Node, error) {
+	objs := l.k8sNodeIndexer.List()
+	nodes := make([]*corev1.Node, 0, len(objs))
+	for _, obj := range objs {
+		if node, ok := obj.(*corev1.Node); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+func (l informerLister) lvmNodes() ([]*lvmapi.LVMNode, error) {
+	objs := l.lvmNodeIndexer.List()
+	nodes := make([]*lvmapi.LVMNode, 0, len(objs))
+	for _, obj := range objs {
+		if node, ok := obj.(*lvmapi.LVMNode); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+func (l informerLister) lvmNode(nodeID string) (*lvmapi.LVMNode, error) {
+	obj, exists, err := l.lvmNodeIndexer.GetByKey(lvmCacheKey(lvm.GetLVMNodeName(nodeID)))
+	if err != nil || !exists {
+		return nil, err
+	}
+	node, ok := obj.(*lvmapi.LVMNode)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T in the lvm node cache", obj)
+	}
+	return node, nil
+}
+
+// lvmVolumes matches the regular expression against the volume groups
+// of the index, rather than against the volume group of every volume.
+func (l informerLister) lvmVolumes(re *regexp.Regexp) ([]*lvmapi.LVMVolume, error) {
+	var vols []*lvmapi.LVMVolume
+	for _, vgName := range l.lvmVolumeIndexer.ListIndexFuncValues(VolumeVolGroupIndex) {
+		if !re.MatchString(vgName) {
+			continue
+		}
+		objs, err := l.lvmVolumeIndexer.ByIndex(VolumeVolGroupIndex, vgName)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if vol, ok := obj.(*lvmapi.LVMVolume); ok {
+				vols = append(vols, vol)
+			}
+		}
+	}
+	return vols, nil
+}
+
+func (l informerLister) groupVolumes(group string) ([]*lvmapi.LVMVolume, error) {
+	return l.indexedVolumes(LabelIndexName(lvm.LVMSpreadGroupKey), group)
+}
+
+// indexedVolumes returns the LVMVolumes having the value in the index.
+func (l informerLister) indexedVolumes(indexName, value string) ([]*lvmapi.LVMVolume, error) {
+	objs, err := l.lvmVolumeIndexer.ByIndex(indexName, value)
+	if err != nil {
+		return nil, err
+	}
+	vols := make([]*lvmapi.LVMVolume, 0, len(objs))
+	for _, obj := range objs {
+		if vol, ok := obj.(*lvmapi.LVMVolume); ok {
+			vols = append(vols, vol)
+		}
+	}
+	return vols, nil
+}
+
+// lvmCacheKey returns the key of the LVM resource of the given name in the
+// informer caches, the resources being in the namespace of the driver.
+func lvmCacheKey(name string) string {
+	if lvm.LvmNamespace == "" {
+		return name
+	}
+	return lvm.LvmNamespace + "/" + name
+}
+
+// VolumeVolGroupIndexFunc indexes the LVMVolumes by their volume group,
+// the volumes whose volume group is not yet selected are indexed with
+// an empty volume group.
+func VolumeVolGroupIndexFunc(obj interface{}) ([]string, error) {
+	vol, ok := obj.(*lvmapi.LVMVolume)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T, expected lvm volume", obj)
+	}
+	return []string{vol.Spec.VolGroup}, nil
+}
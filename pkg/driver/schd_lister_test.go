@@ -0,0 +1,225 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/builder/nodebuilder"
+	"github.com/openebs/lvm-localpv/pkg/builder/volbuilder"
+	clientset "github.com/openebs/lvm-localpv/pkg/generated/clientset/internalclientset"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// testSchedulingObjects returns the LVMNodes and the LVMVolumes of
+// a cluster of the given size, with two volume groups per node.
+func testSchedulingObjects(nodes, volumes int) ([]lvmapi.LVMNode, []lvmapi.LVMVolume) {
+	lvmNodes := make([]lvmapi.LVMNode, nodes)
+	for i := range lvmNodes {
+		lvmNodes[i] = lvmapi.LVMNode{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)},
+			VolumeGroups: []lvmapi.VolumeGroup{
+				{Name: "lvmvg", Free: *resource.NewQuantity(int64(i+1)*Gi, resource.BinarySI)},
+				{Name: "othervg", Free: *resource.NewQuantity(100*Gi, resource.BinarySI)},
+			},
+		}
+	}
+	lvmVolumes := make([]lvmapi.LVMVolume, volumes)
+	for i := range lvmVolumes {
+		vg := "lvmvg"
+		if i%2 == 1 {
+			vg = "othervg"
+		}
+		lvmVolumes[i] = lvmapi.LVMVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pvc-%d", i)},
+			Spec: lvmapi.VolumeInfo{
+				OwnerNodeID: fmt.Sprintf("node-%d", i%nodes),
+				VolGroup:    vg,
+				Capacity:    strconv.FormatInt(Gi, 10),
+			},
+		}
+	}
+	return lvmNodes, lvmVolumes
+}
+
+// newTestInformerLister returns an informer lister
+// whose caches hold the given objects.
+func newTestInformerLister(t testing.TB, nodes []lvmapi.LVMNode, volumes []lvmapi.LVMVolume) informerLister {
+	l := informerLister{
+		k8sNodeIndexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+		lvmNodeIndexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+		lvmVolumeIndexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+			VolumeVolGroupIndex:                   VolumeVolGroupIndexFunc,
+			LabelIndexName(lvm.LVMSpreadGroupKey): LabelIndexFunc(lvm.LVMSpreadGroupKey),
+		}),
+	}
+	for i := range nodes {
+		assert.NoError(t, l.lvmNodeIndexer.Add(&nodes[i]))
+		assert.NoError(t, l.k8sNodeIndexer.Add(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   nodes[i].Name,
+				Labels: map[string]string{"kubernetes.io/hostname": nodes[i].Name},
+			},
+		}))
+	}
+	for i := range volumes {
+		assert.NoError(t, l.lvmVolumeIndexer.Add(&volumes[i]))
+	}
+	return l
+}
+
+// newTestAPILister returns an API lister whose
+// requests are served with the given objects.
+func newTestAPILister(t testing.TB, nodes []lvmapi.LVMNode, volumes []lvmapi.LVMVolume) (apiLister, func()) {
+	nodeList, err := json.Marshal(&lvmapi.LVMNodeList{Items: nodes})
+	assert.NoError(t, err)
+	volumeList, err := json.Marshal(&lvmapi.LVMVolumeList{Items: volumes})
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/lvmnodes"):
+			_, _ = w.Write(nodeList)
+		case strings.HasSuffix(r.URL.Path, "/lvmvolumes"):
+			_, _ = w.Write(volumeList)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	cs, err := clientset.NewForConfig(&rest.Config{Host: srv.URL})
+	assert.NoError(t, err)
+	return apiLister{
+		nodes:   nodebuilder.NewKubeclient(nodebuilder.WithClientSet(cs)),
+		volumes: volbuilder.NewKubeclient(volbuilder.WithClientSet(cs)),
+	}, srv.Close
+}
+
+// withSchedLister sets the lister of the schedulers
+// and returns a function restoring the previous one.
+func withSchedLister(l schedulingLister) func() {
+	prev := schedLister
+	schedLister = l
+	return func() { schedLister = prev }
+}
+
+func TestInformerLister(t *testing.T) {
+	nodes, volumes := testSchedulingObjects(4, 10)
+	volumes[9].Spec.VolGroup = ""
+	il := newTestInformerLister(t, nodes, volumes)
+	al, closeServer := newTestAPILister(t, nodes, volumes)
+	defer closeServer()
+
+	for _, l := range []schedulingLister{il, al} {
+		vols, err := l.lvmVolumes(regexp.MustCompile("^lvmvg$"))
+		assert.NoError(t, err)
+		assert.Len(t, vols, 5)
+
+		// the volumes not yet having a volume group match the empty pattern.
+		vols, err = l.lvmVolumes(regexp.MustCompile("^$"))
+		assert.NoError(t, err)
+		assert.Len(t, vols, 1)
+
+		lvmNodes, err := l.lvmNodes()
+		assert.NoError(t, err)
+		assert.Len(t, lvmNodes, 4)
+	}
+
+	node, err := il.lvmNode("node-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "node-2", node.Name)
+	node, err = il.lvmNode("node-9")
+	assert.NoError(t, err)
+	assert.Nil(t, node)
+
+	defer withSchedLister(il)()
+	nmap, err := getVolumeWeightedMap(regexp.MustCompile("^lvmvg$"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"node-0": 3, "node-2": 2}, nmap)
+
+	// node-3 has no volume, node-1 does not satisfy the topology.
+	var topo []*csi.Topology
+	for _, node := range []string{"node-0", "node-2", "node-3"} {
+		topo = append(topo, &csi.Topology{
+			Segments: map[string]string{"kubernetes.io/hostname": node},
+		})
+	}
+	selected, err := selectNodes(&csi.CreateVolumeRequest{
+		AccessibilityRequirements: &csi.TopologyRequirement{Preferred: topo},
+	}, nmap)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-3", "node-2", "node-0"}, selected)
+}
+
+func benchmarkSchedulingMaps(b *testing.B, l schedulingLister) {
+	defer withSchedLister(l)()
+	params, err := NewVolumeParams(map[string]string{"vgpattern": "^lvmvg$"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := getVolumeWeightedMap(params.VgPattern); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := getCapacityWeightedMap(params.VgPattern); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := getSpaceWeightedMap(params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSchedulingMaps compares building the maps of the schedulers
+// from the API server with building them from the informer caches.
+func BenchmarkSchedulingMaps(b *testing.B) {
+	nodes, volumes := testSchedulingObjects(300, 4000)
+	b.Run("API", func(b *testing.B) {
+		l, closeServer := newTestAPILister(b, nodes, volumes)
+		defer closeServer()
+		benchmarkSchedulingMaps(b, l)
+	})
+	b.Run("Informer", func(b *testing.B) {
+		benchmarkSchedulingMaps(b, newTestInformerLister(b, nodes, volumes))
+	})
+}
+
+func Test_lvmCacheKey(t *testing.T) {
+	defer func(ns string) { lvm.LvmNamespace = ns }(lvm.LvmNamespace)
+
+	lvm.LvmNamespace = ""
+	assert.Equal(t, "pvc-1", lvmCacheKey("pvc-1"))
+	lvm.LvmNamespace = "openebs"
+	assert.Equal(t, "openebs/pvc-1", lvmCacheKey("pvc-1"))
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxWeightedScore is the score of the least preferred
+// node of the combined schedulers.
+const maxWeightedScore = 1000000
+
+// NodeScheduler computes the weights of the nodes for provisioning a
+// volume. The nodes with lower weights are preferred, and the nodes
+// missing in the returned map are preferred over all the others.
+type NodeScheduler interface {
+	NodeWeights(params *VolumeParams, capacity int64) (map[string]int64, error)
+}
+
+// NodeSchedulerFunc is an adapter allowing the use of
+// a function as a NodeScheduler.
+type NodeSchedulerFunc func(params *VolumeParams, capacity int64) (map[string]int64, error)
+
+// NodeWeights calls f(params, capacity).
+func (f NodeSchedulerFunc) NodeWeights(params *VolumeParams, capacity int64) (map[string]int64, error) {
+	return f(params, capacity)
+}
+
+// VolumeGroupSelector is implemented by the schedulers which also select
+// the volume group of the volume on the node they picked. The volume group
+// is only a hint, the node agent falls back to the other volume groups
+// matching the volume if it can not be provisioned in it.
+type VolumeGroupSelector interface {
+	VolumeGroup(node, volName string, params *VolumeParams, capacity int64) (string, error)
+}
+
+// schedulers is the registry of the schedulers
+// which can be set in the storage classes.
+var schedulers = struct {
+	sync.RWMutex
+	byName map[string]NodeScheduler
+}{
+	byName: map[string]NodeScheduler{
+		VolumeWeighted: NodeSchedulerFunc(func(params *VolumeParams, _ int64) (map[string]int64, error) {
+			return getVolumeWeightedMap(params.VgPattern)
+		}),
+		CapacityWeighted: NodeSchedulerFunc(func(params *VolumeParams, _ int64) (map[string]int64, error) {
+			return getCapacityWeightedMap(params.VgPattern)
+		}),
+		SpaceWeighted: NodeSchedulerFunc(func(params *VolumeParams, _ int64) (map[string]int64, error) {
+			return getSpaceWeightedMap(params)
+		}),
+		BinPacking: binPackingScheduler{},
+	},
+}
+
+// RegisterScheduler registers the scheduler under the given name,
+// so that it can be set in the scheduler param of the storage classes.
+// It panics if a scheduler is already registered with the same name.
+func RegisterScheduler(name string, s NodeScheduler) {
+	schedulers.Lock()
+	defer schedulers.Unlock()
+	if _, ok := schedulers.byName[name]; ok {
+		panic(fmt.Sprintf("scheduler %s is already registered", name))
+	}
+	schedulers.byName[name] = s
+}
+
+// getScheduler returns the scheduler registered with the given name.
+func getScheduler(name string) (NodeScheduler, bool) {
+	schedulers.RLock()
+	defer schedulers.RUnlock()
+	s, ok := schedulers.byName[name]
+	return s, ok
+}
+
+// schedulerNames returns the sorted names of the registered schedulers.
+func schedulerNames() []string {
+	schedulers.RLock()
+	defer schedulers.RUnlock()
+	names := make([]string, 0, len(schedulers.byName))
+	for name := range schedulers.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// weightedScheduler combines the weights of several schedulers.
+type weightedScheduler struct {
+	schedulers []NodeScheduler
+	weights    []int64
+}
+
+// parseScheduler parses the scheduler param of the storage class, either
+// the name of a scheduler or a comma separated list of schedulers with
+// their weights, e.g. "SpaceWeighted:70,VolumeWeighted:30".
+func parseScheduler(value string) (NodeScheduler, error) {
+	ws := &weightedScheduler{}
+	for _, entry := range strings.Split(value, ",") {
+		name, weight := strings.TrimSpace(entry), int64(1)
+		if i := strings.Index(name, ":"); i >= 0 {
+			var err error
+			if weight, err = strconv.ParseInt(strings.TrimSpace(name[i+1:]), 10, 64); err != nil || weight <= 0 {
+				return nil, fmt.Errorf("weight of the scheduler %s should be a positive integer", name)
+			}
+			name = strings.TrimSpace(name[:i])
+		}
+		s, ok := getScheduler(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown scheduler %q, should be one of %s",
+				name, strings.Join(schedulerNames(), ", "))
+		}
+		ws.schedulers = append(ws.schedulers, s)
+		ws.weights = append(ws.weights, weight)
+	}
+	if len(ws.schedulers) == 1 {
+		return ws.schedulers[0], nil
+	}
+	return ws, nil
+}
+
+// NodeWeights returns the weighted sum of the weights of the schedulers,
+// once normalized to the same scale. The weights of a scheduler are
+// normalized between its least and most weighted nodes, and the nodes
+// missing in its weights get the lowest score, so that they remain the
+// most preferred ones.
+func (ws *weightedScheduler) NodeWeights(params *VolumeParams, capacity int64) (map[string]int64, error) {
+	var total int64
+	for _, weight := range ws.weights {
+		total += weight
+	}
+
+	scores := map[string]float64{}
+	for i, s := range ws.schedulers {
+		nmap, err := s.NodeWeights(params, capacity)
+		if err != nil {
+			return nil, err
+		}
+		if len(nmap) == 0 {
+			continue
+		}
+
+		least, most := int64(math.MaxInt64), int64(math.MinInt64)
+		for _, w := range nmap {
+			if w < least {
+				least = w
+			}
+			if w > most {
+				most = w
+			}
+		}
+
+		share := float64(ws.weights[i]) / float64(total)
+		for node, w := range nmap {
+			// the least weighted node still scores above the missing ones.
+			scores[node] += share * (float64(w-least) + 1) / (float64(most-least) + 1)
+		}
+	}
+
+	weights := make(map[string]int64, len(scores))
+	for node, score := range scores {
+		weights[node] = int64(score * maxWeightedScore)
+	}
+	return weights, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedScheduler returns a scheduler always returning the given weights.
+func fixedScheduler(nmap map[string]int64) NodeScheduler {
+	return NodeSchedulerFunc(func(*VolumeParams, int64) (map[string]int64, error) {
+		return nmap, nil
+	})
+}
+
+func TestParseScheduler(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		weights []int64
+		wantErr bool
+	}{
+		"single":            {value: VolumeWeighted},
+		"single weighted":   {value: " CapacityWeighted:5 "},
+		"combined":          {value: "SpaceWeighted:70, VolumeWeighted:30", weights: []int64{70, 30}},
+		"default weight":    {value: "SpaceWeighted,VolumeWeighted:3", weights: []int64{1, 3}},
+		"unknown":           {value: "spaceweighted", wantErr: true},
+		"unknown combined":  {value: "SpaceWeighted:70,Random:30", wantErr: true},
+		"zero weight":       {value: "SpaceWeighted:0,VolumeWeighted:1", wantErr: true},
+		"invalid weight":    {value: "SpaceWeighted:high", wantErr: true},
+		"empty":             {value: "", wantErr: true},
+		"empty combination": {value: "SpaceWeighted,", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := parseScheduler(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			ws, ok := s.(*weightedScheduler)
+			assert.Equal(t, tt.weights != nil, ok)
+			if ok {
+				assert.Equal(t, tt.weights, ws.weights)
+			}
+		})
+	}
+}
+
+func TestWeightedScheduler(t *testing.T) {
+	ws := &weightedScheduler{
+		schedulers: []NodeScheduler{
+			// node-1 has the most free space
+			fixedScheduler(map[string]int64{"node-1": 100, "node-2": 200, "node-3": 300}),
+			// node-3 has the least volumes, node-4 has none
+			fixedScheduler(map[string]int64{"node-1": 10, "node-2": 5, "node-3": 1}),
+		},
+		weights: []int64{70, 30},
+	}
+	nmap, err := ws.NodeWeights(&VolumeParams{}, Gi)
+	assert.NoError(t, err)
+	assert.Len(t, nmap, 3)
+	assert.Less(t, nmap["node-1"], nmap["node-2"])
+	assert.Less(t, nmap["node-2"], nmap["node-3"])
+
+	// the volume counts take over once weighted more.
+	ws.weights = []int64{30, 70}
+	nmap, err = ws.NodeWeights(&VolumeParams{}, Gi)
+	assert.NoError(t, err)
+	assert.Less(t, nmap["node-3"], nmap["node-1"])
+	assert.Less(t, nmap["node-2"], nmap["node-1"])
+	assert.LessOrEqual(t, nmap["node-1"], int64(maxWeightedScore))
+}
+
+func TestRegisterScheduler(t *testing.T) {
+	RegisterScheduler("TestFixed", fixedScheduler(map[string]int64{"node-1": 1}))
+	defer func() {
+		schedulers.Lock()
+		delete(schedulers.byName, "TestFixed")
+		schedulers.Unlock()
+	}()
+
+	params, err := NewVolumeParams(map[string]string{"scheduler": "TestFixed"})
+	assert.NoError(t, err)
+	nmap, err := params.Scheduler.NodeWeights(params, Gi)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"node-1": 1}, nmap)
+
+	assert.Panics(t, func() { RegisterScheduler(SpaceWeighted, fixedScheduler(nil)) })
+}
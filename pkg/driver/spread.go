@@ -0,0 +1,208 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// volume spreading constants
+const (
+	// SpreadByStatefulSet spreads the volumes created from the same
+	// volume claim template of a StatefulSet.
+	SpreadByStatefulSet = "StatefulSet"
+
+	// SpreadByLabel spreads the volumes whose PVCs have
+	// the same value of the spreadLabel label.
+	SpreadByLabel = "Label"
+
+	// SpreadPolicyPreferred prefers the domains having the least volumes
+	// of the group, but still provisions the volume in a domain already
+	// having one if there is no other domain available.
+	SpreadPolicyPreferred = "Preferred"
+
+	// SpreadPolicyRequired fails the provisioning of the volume if
+	// every domain available already has a volume of the group.
+	SpreadPolicyRequired = "Required"
+
+	// DefaultSpreadTopologyKey spreads the volumes across the nodes.
+	DefaultSpreadTopologyKey = "kubernetes.io/hostname"
+
+	// spreadGroupHashLen is the number of hex characters of the hash
+	// of the spread group, fitting in a label value.
+	spreadGroupHashLen = 32
+)
+
+// validateSpreadParams validates the spread parameters of the volume.
+func validateSpreadParams(params *VolumeParams) error {
+	switch params.SpreadBy {
+	case "", SpreadByStatefulSet:
+	case SpreadByLabel:
+		if errs := validation.IsQualifiedName(params.SpreadLabel); len(errs) != 0 {
+			return fmt.Errorf("invalid spreadLabel param %q: %s",
+				params.SpreadLabel, strings.Join(errs, ", "))
+		}
+	default:
+		return fmt.Errorf("invalid spreadBy param %v, should be %s or %s",
+			params.SpreadBy, SpreadByStatefulSet, SpreadByLabel)
+	}
+	if errs := validation.IsQualifiedName(params.SpreadTopologyKey); len(errs) != 0 {
+		return fmt.Errorf("invalid spreadTopologyKey param %q: %s",
+			params.SpreadTopologyKey, strings.Join(errs, ", "))
+	}
+	if params.SpreadPolicy != SpreadPolicyPreferred &&
+		params.SpreadPolicy != SpreadPolicyRequired {
+		return fmt.Errorf("invalid spreadPolicy param %v, should be %s or %s",
+			params.SpreadPolicy, SpreadPolicyPreferred, SpreadPolicyRequired)
+	}
+	return nil
+}
+
+// spreadGroup returns the hash of the group the volume is spread from,
+// given the labels of its PVC and the StatefulSets of its namespace. It
+// returns an empty group if the volume is not spread, or its PVC does not
+// belong to a group.
+func (p *VolumeParams) spreadGroup(pvcLabels map[string]string,
+	statefulSets []*appsv1.StatefulSet) string {
+	if p.PVCName == "" || p.PVCNamespace == "" {
+		return ""
+	}
+	var group string
+	switch p.SpreadBy {
+	case SpreadByStatefulSet:
+		claim := statefulSetClaim(statefulSets, p.PVCName)
+		if claim == "" {
+			return ""
+		}
+		group = SpreadByStatefulSet + "/" + p.PVCNamespace + "/" + claim
+	case SpreadByLabel:
+		value, ok := pvcLabels[p.SpreadLabel]
+		if !ok {
+			return ""
+		}
+		group = SpreadByLabel + "/" + p.PVCNamespace + "/" + p.SpreadLabel + "=" + value
+	default:
+		return ""
+	}
+	// the volumes are spread per topology key, so that the same group
+	// spread with different keys is not mixed up.
+	sum := sha256.Sum256([]byte(group + "/" + p.SpreadTopologyKey))
+	return hex.EncodeToString(sum[:])[:spreadGroupHashLen]
+}
+
+// statefulSetClaim returns the StatefulSet and the volume claim template,
+// as <statefulset>/<template>, the PVC is created from, or an empty string
+// if the PVC is not named <template>-<statefulset>-<ordinal> after any of
+// the StatefulSets.
+func statefulSetClaim(statefulSets []*appsv1.StatefulSet, pvcName string) string {
+	for _, sts := range statefulSets {
+		for _, template := range sts.Spec.VolumeClaimTemplates {
+			prefix := template.Name + "-" + sts.Name + "-"
+			ordinal := strings.TrimPrefix(pvcName, prefix)
+			if ordinal == pvcName || ordinal == "" ||
+				strings.Trim(ordinal, "0123456789") != "" {
+				continue
+			}
+			return sts.Name + "/" + template.Name
+		}
+	}
+	return ""
+}
+
+// spreadNodes orders the nodes selected by the scheduler by the number of
+// volumes of the group of the volume already present in their topology
+// domains, keeping the order of the scheduler among the nodes of the
+// domains having as many volumes. With the Required policy, the nodes of
+// the domains already having a volume of the group are removed. The
+// nodes without the topology key are put last, or removed if required.
+func spreadNodes(selected []string, params *VolumeParams, volName string) ([]string, error) {
+	k8sNodes, err := schedLister.k8sNodes()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list the nodes: %v", err)
+	}
+	domains := make(map[string]string, len(k8sNodes))
+	for _, node := range k8sNodes {
+		if domain, ok := node.Labels[params.SpreadTopologyKey]; ok {
+			domains[node.Name] = domain
+		}
+	}
+
+	vols, err := schedLister.groupVolumes(params.SpreadGroup)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list the volumes of the spread group: %v", err)
+	}
+	// the volumes just scheduled may not be in the caches yet.
+	owners := capacityReservations.groupNodes(params.SpreadGroup)
+	for _, vol := range vols {
+		owners[vol.Name] = vol.Spec.OwnerNodeID
+	}
+	delete(owners, volName)
+
+	counts := map[string]int{}
+	for _, node := range owners {
+		if domain, ok := domains[node]; ok {
+			counts[domain]++
+		}
+	}
+
+	nodes := make([]string, 0, len(selected))
+	for _, node := range selected {
+		domain, ok := domains[node]
+		if params.SpreadPolicy == SpreadPolicyRequired && (!ok || counts[domain] > 0) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"every %s domain available already has a volume spread with the volume %s",
+			params.SpreadTopologyKey, volName)
+	}
+
+	rank := func(node string) int {
+		if domain, ok := domains[node]; ok {
+			return counts[domain]
+		}
+		return len(owners) + 1
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return rank(nodes[i]) < rank(nodes[j])
+	})
+	klog.V(4).Infof("spread the nodes of the volume %s: %v", volName, nodes)
+	return nodes, nil
+}
+
+// spreadLabels returns the labels of the LVMVolume
+// recording the group it is spread from.
+func (p *VolumeParams) spreadLabels() map[string]string {
+	if p.SpreadGroup == "" {
+		return nil
+	}
+	return map[string]string{lvm.LVMSpreadGroupKey: p.SpreadGroup}
+}
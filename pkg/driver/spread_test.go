@@ -0,0 +1,218 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+
+	lvmapi "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+func TestSpreadParams(t *testing.T) {
+	tests := map[string]struct {
+		params  map[string]string
+		wantErr bool
+	}{
+		"not spread":   {params: map[string]string{}},
+		"statefulset":  {params: map[string]string{"spreadBy": "StatefulSet", "spreadPolicy": "Required"}},
+		"label":        {params: map[string]string{"spreadBy": "Label", "spreadLabel": "app.kubernetes.io/instance"}},
+		"zone":         {params: map[string]string{"spreadBy": "StatefulSet", "spreadTopologyKey": "topology.kubernetes.io/zone"}},
+		"unknown":      {params: map[string]string{"spreadBy": "statefulset"}, wantErr: true},
+		"no label":     {params: map[string]string{"spreadBy": "Label"}, wantErr: true},
+		"invalid key":  {params: map[string]string{"spreadBy": "StatefulSet", "spreadTopologyKey": "zone/"}, wantErr: true},
+		"invalid mode": {params: map[string]string{"spreadBy": "StatefulSet", "spreadPolicy": "always"}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewVolumeParams(tt.params)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestSpreadGroup(t *testing.T) {
+	pvcParams := func(params map[string]string, pvcName string) *VolumeParams {
+		params["csi.storage.k8s.io/pvc/name"] = pvcName
+		params["csi.storage.k8s.io/pvc/namespace"] = "db"
+		p, err := NewVolumeParams(params)
+		assert.NoError(t, err)
+		return p
+	}
+	sets := []*appsv1.StatefulSet{
+		testStatefulSet("mysql", "data", "logs"),
+		testStatefulSet("my", "data-sql"),
+	}
+	sts := map[string]string{"spreadBy": SpreadByStatefulSet}
+	group := pvcParams(sts, "data-mysql-0").spreadGroup(nil, sets)
+	assert.Len(t, group, spreadGroupHashLen)
+	assert.Equal(t, group, pvcParams(sts, "data-mysql-2").spreadGroup(nil, sets))
+	assert.NotEqual(t, group, pvcParams(sts, "logs-mysql-0").spreadGroup(nil, sets))
+	assert.NotEqual(t, group, pvcParams(sts, "data-sql-my-0").spreadGroup(nil, sets))
+	assert.Empty(t, pvcParams(sts, "data-mysql").spreadGroup(nil, sets))
+	assert.Empty(t, pvcParams(sts, "data-mysql-a").spreadGroup(nil, sets))
+	// the PVCs not created by a StatefulSet are not grouped by their name
+	assert.Empty(t, pvcParams(sts, "backup-2024").spreadGroup(nil, sets))
+	assert.Empty(t, pvcParams(sts, "data-mysql-0").spreadGroup(nil, nil))
+	assert.NotEqual(t, group, pvcParams(map[string]string{
+		"spreadBy":          SpreadByStatefulSet,
+		"spreadTopologyKey": "topology.kubernetes.io/zone",
+	}, "data-mysql-0").spreadGroup(nil, sets))
+
+	byLabel := map[string]string{"spreadBy": SpreadByLabel, "spreadLabel": "app"}
+	group = pvcParams(byLabel, "data-1").spreadGroup(map[string]string{"app": "mysql"}, nil)
+	assert.NotEmpty(t, group)
+	assert.Equal(t, group, pvcParams(byLabel, "other").spreadGroup(map[string]string{"app": "mysql"}, nil))
+	assert.NotEqual(t, group, pvcParams(byLabel, "data-1").spreadGroup(map[string]string{"app": "pg"}, nil))
+	assert.Empty(t, pvcParams(byLabel, "data-1").spreadGroup(nil, nil))
+
+	p, err := NewVolumeParams(map[string]string{"spreadBy": SpreadByStatefulSet})
+	assert.NoError(t, err)
+	assert.Empty(t, p.spreadGroup(nil, sets), "no pvc metadata")
+}
+
+// testStatefulSet returns a StatefulSet of the
+// db namespace with the volume claim templates.
+func testStatefulSet(name string, templates ...string) *appsv1.StatefulSet {
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "db"},
+	}
+	for _, template := range templates {
+		sts.Spec.VolumeClaimTemplates = append(sts.Spec.VolumeClaimTemplates,
+			corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: template}})
+	}
+	return sts
+}
+
+func TestGetVolumeParamsSpreadGroup(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	cs := &controller{
+		kubeClient: kubeClient,
+		statefulSetLister: appslisters.NewStatefulSetLister(newTestInformer(t,
+			&appsv1.StatefulSet{}, testStatefulSet("mysql", "data")).GetIndexer()),
+	}
+	getGroup := func(pvcName string) string {
+		params, err := cs.getVolumeParams(context.Background(), &csi.CreateVolumeRequest{
+			Parameters: map[string]string{
+				"volgroup":                         "lvmvg",
+				"spreadBy":                         SpreadByStatefulSet,
+				"csi.storage.k8s.io/pvc/name":      pvcName,
+				"csi.storage.k8s.io/pvc/namespace": "db",
+			},
+		})
+		assert.NoError(t, err)
+		return params.SpreadGroup
+	}
+	assert.NotEmpty(t, getGroup("data-mysql-0"))
+	assert.Empty(t, getGroup("backup-2024"))
+	// the statefulsets are read from the cache.
+	assert.Empty(t, kubeClient.Actions())
+}
+
+func TestSpreadNodes(t *testing.T) {
+	nodes, _ := testSchedulingObjects(4, 0)
+	il := newTestInformerLister(t, nodes, []lvmapi.LVMVolume{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "pvc-1",
+				Labels: map[string]string{lvm.LVMSpreadGroupKey: "group"},
+			},
+			Spec: lvmapi.VolumeInfo{OwnerNodeID: "node-0"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "pvc-2",
+				Labels: map[string]string{lvm.LVMSpreadGroupKey: "other"},
+			},
+			Spec: lvmapi.VolumeInfo{OwnerNodeID: "node-1"},
+		},
+	})
+	// node-3 is in the zone of node-0, node-2 has no zone.
+	for name, zone := range map[string]string{"node-0": "a", "node-1": "b", "node-3": "a"} {
+		assert.NoError(t, il.k8sNodeIndexer.Update(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"kubernetes.io/hostname":      name,
+					"topology.kubernetes.io/zone": zone,
+				},
+			},
+		}))
+	}
+	defer withSchedLister(il)()
+
+	// pvc-3 has just been scheduled on node-1.
+	capacityReservations.reserve("pvc-3", "node-1", "lvmvg", "group", Gi)
+	defer capacityReservations.release("pvc-3")
+
+	selected := []string{"node-0", "node-1", "node-2", "node-3"}
+	tests := map[string]struct {
+		params  *VolumeParams
+		volName string
+		want    []string
+		code    codes.Code
+	}{
+		"preferred": {
+			params:  &VolumeParams{SpreadTopologyKey: DefaultSpreadTopologyKey, SpreadPolicy: SpreadPolicyPreferred},
+			volName: "pvc-4",
+			want:    []string{"node-2", "node-3", "node-0", "node-1"},
+		},
+		"required": {
+			params:  &VolumeParams{SpreadTopologyKey: DefaultSpreadTopologyKey, SpreadPolicy: SpreadPolicyRequired},
+			volName: "pvc-4",
+			want:    []string{"node-2", "node-3"},
+		},
+		"rescheduled": {
+			params:  &VolumeParams{SpreadTopologyKey: DefaultSpreadTopologyKey, SpreadPolicy: SpreadPolicyRequired},
+			volName: "pvc-3",
+			want:    []string{"node-1", "node-2", "node-3"},
+		},
+		"zone preferred": {
+			params:  &VolumeParams{SpreadTopologyKey: "topology.kubernetes.io/zone", SpreadPolicy: SpreadPolicyPreferred},
+			volName: "pvc-4",
+			want:    []string{"node-0", "node-1", "node-3", "node-2"},
+		},
+		"zone required": {
+			params:  &VolumeParams{SpreadTopologyKey: "topology.kubernetes.io/zone", SpreadPolicy: SpreadPolicyRequired},
+			volName: "pvc-4",
+			code:    codes.ResourceExhausted,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.params.SpreadGroup = "group"
+			nodes, err := spreadNodes(selected, tt.params, tt.volName)
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, nodes)
+		})
+	}
+}
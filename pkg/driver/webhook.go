@@ -0,0 +1,311 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openebs/lib-csi/pkg/common/helpers"
+	admissionv1 "k8s.io/api/admission/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+const (
+	// WebhookValidatePath is the path of the webhook server validating
+	// the StorageClasses and the VolumeSnapshotClasses of the driver.
+	WebhookValidatePath = "/validate"
+
+	// csiParamPrefix is the prefix of the parameters
+	// reserved to the CSI sidecars.
+	csiParamPrefix = "csi.storage.k8s.io/"
+
+	// maxAdmissionReviewSize is the maximum size of
+	// the admission review requests read by the webhook.
+	maxAdmissionReviewSize = 1 << 20
+)
+
+// storageClassParams are the parameters supported in the storage classes.
+var storageClassParams = map[string]bool{
+	"storage":               true,
+	"fstype":                true,
+	"vgpattern":             true,
+	"volgroup":              true,
+	"shared":                true,
+	"thinprovision":         true,
+	"scheduler":             true,
+	"overprovisioningratio": true,
+	"thinpoolthreshold":     true,
+	"deletionpolicy":        true,
+	AllowedOverridesKey:     true,
+	"lvnametemplate":        true,
+	"maxreschedules":        true,
+	"spreadby":              true,
+	"spreadlabel":           true,
+	"spreadtopologykey":     true,
+	"spreadpolicy":          true,
+}
+
+// snapshotClassParams are the parameters supported in the snapshot classes.
+var snapshotClassParams = map[string]bool{
+	"snapsize": true,
+}
+
+// volumeSnapshotClass holds the fields of the VolumeSnapshotClass
+// validated by the webhook.
+type volumeSnapshotClass struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Driver     string            `json:"driver"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// checkUnknownParams returns an error listing the parameters, other than
+// the ones reserved to the CSI sidecars, which are not known.
+func checkUnknownParams(m map[string]string, known map[string]bool) error {
+	var unknown []string
+	for key := range m {
+		if !known[key] && !strings.HasPrefix(key, csiParamPrefix) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown parameters %s", strings.Join(unknown, ", "))
+}
+
+// checkYesNoParam returns an error if the value of
+// the parameter is neither yes nor no.
+func checkYesNoParam(name, value string) error {
+	if value != lvm.YES && value != "no" {
+		return fmt.Errorf("invalid %s param %v, should be yes or no", name, value)
+	}
+	return nil
+}
+
+// ValidateStorageClassParams validates the parameters of a storage class
+// of the driver. Other than the checks done while provisioning the volumes,
+// it rejects the unknown parameters and the values which are silently
+// ignored while provisioning.
+func ValidateStorageClassParams(m map[string]string) error {
+	m = helpers.GetCaseInsensitiveMap(&m)
+	if err := checkUnknownParams(m, storageClassParams); err != nil {
+		return err
+	}
+
+	params, err := NewVolumeParams(m)
+	if err != nil {
+		return err
+	}
+
+	if m["vgpattern"] == "" && m["volgroup"] == "" {
+		return fmt.Errorf("one of the vgpattern or volgroup params is required")
+	}
+	if err = checkYesNoParam("shared", params.Shared); err != nil {
+		return err
+	}
+	if err = checkYesNoParam("thinProvision", params.ThinProvision); err != nil {
+		return err
+	}
+
+	for _, key := range strings.Split(m[AllowedOverridesKey], ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if !storageClassParams[key] || key == AllowedOverridesKey {
+			return fmt.Errorf("invalid allowedOverrides param, %s can not be overridden", key)
+		}
+	}
+
+	// render the template for a sample pvc, so that the
+	// templates failing for every volume are caught.
+	if params.LVNameTemplate != nil {
+		params.PVCName, params.PVCNamespace = "pvc", "default"
+		if _, err = params.renderLVName("pvc-00000000-0000-0000-0000-000000000000"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSnapshotClassParams validates the parameters
+// of a volume snapshot class of the driver.
+func ValidateSnapshotClassParams(m map[string]string) error {
+	m = helpers.GetCaseInsensitiveMap(&m)
+	if err := checkUnknownParams(m, snapshotClassParams); err != nil {
+		return err
+	}
+	_, err := NewSnapshotParams(m)
+	return err
+}
+
+// validateAdmission validates the StorageClass or the VolumeSnapshotClass
+// of the admission request. The objects of the other drivers are allowed.
+func validateAdmission(driverName string, req *admissionv1.AdmissionRequest) error {
+	switch req.Kind.Kind {
+	case "StorageClass":
+		sc := &storagev1.StorageClass{}
+		if err := json.Unmarshal(req.Object.Raw, sc); err != nil {
+			return fmt.Errorf("failed to decode the storage class: %v", err)
+		}
+		if sc.Provisioner != driverName {
+			return nil
+		}
+		if err := ValidateStorageClassParams(sc.Parameters); err != nil {
+			return fmt.Errorf("invalid storage class %s: %v", sc.Name, err)
+		}
+	case "VolumeSnapshotClass":
+		vsc := &volumeSnapshotClass{}
+		if err := json.Unmarshal(req.Object.Raw, vsc); err != nil {
+			return fmt.Errorf("failed to decode the volume snapshot class: %v", err)
+		}
+		if vsc.Driver != driverName {
+			return nil
+		}
+		if err := ValidateSnapshotClassParams(vsc.Parameters); err != nil {
+			return fmt.Errorf("invalid volume snapshot class %s: %v", vsc.Name, err)
+		}
+	}
+	return nil
+}
+
+// webhookHandler returns the handler of the admission reviews
+// validating the classes of the driver.
+func webhookHandler(driverName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxAdmissionReviewSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := &admissionv1.AdmissionReview{}
+		if err = json.Unmarshal(body, review); err != nil || review.Request == nil {
+			http.Error(w, "invalid admission review", http.StatusBadRequest)
+			return
+		}
+
+		resp := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+		if err = validateAdmission(driverName, review.Request); err != nil {
+			klog.Infof("rejecting %s %s: %v", review.Request.Kind.Kind, review.Request.Name, err)
+			resp.Allowed = false
+			resp.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: err.Error(),
+				Code:    http.StatusUnprocessableEntity,
+			}
+		}
+
+		review.Request = nil
+		review.Response = resp
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(review); err != nil {
+			klog.Errorf("failed to write the admission review: %v", err)
+		}
+	}
+}
+
+// certLoader loads the TLS certificate of the webhook, and loads it
+// again when its files change, e.g. when cert-manager renews it.
+type certLoader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// getCertificate returns the certificate of the webhook, reloaded if its
+// files have changed. The certificate loaded last is kept until the new
+// one can be loaded, since the certificate and the key are not updated
+// at once.
+func (l *certLoader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var modTime time.Time
+	for _, file := range []string{l.certFile, l.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			if l.cert != nil {
+				return l.cert, nil
+			}
+			return nil, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if l.cert != nil && modTime.Equal(l.modTime) {
+		return l.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		if l.cert != nil {
+			klog.Errorf("failed to reload the webhook certificate, serving the previous one: %v", err)
+			return l.cert, nil
+		}
+		return nil, err
+	}
+	if l.cert != nil {
+		klog.Infof("reloaded the webhook certificate from %s", l.certFile)
+	}
+	l.cert, l.modTime = &cert, modTime
+	return l.cert, nil
+}
+
+// serveWebhook starts the TLS server of the admission webhook
+// validating the StorageClasses and the VolumeSnapshotClasses.
+func serveWebhook(listenAddr, certFile, keyFile, driverName string) {
+	mux := http.NewServeMux()
+	mux.Handle(WebhookValidatePath, webhookHandler(driverName))
+
+	loader := &certLoader{certFile: certFile, keyFile: keyFile}
+	if _, err := loader.getCertificate(nil); err != nil {
+		klog.Fatalf("Failed to load the admission webhook certificate: %s", err.Error())
+	}
+	server := &http.Server{
+		Addr:              listenAddr,
+		Handler:           mux,
+		TLSConfig:         &tls.Config{GetCertificate: loader.getCertificate},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		klog.Infof("starting the admission webhook server at %s", listenAddr)
+		if err := server.ListenAndServeTLS("", ""); err != nil {
+			klog.Fatalf("Failed to start the admission webhook server at %q: %s", listenAddr, err.Error())
+		}
+	}()
+}
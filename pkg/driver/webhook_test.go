@@ -0,0 +1,242 @@
+/*
+Copyright 2020 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidateStorageClassParams(t *testing.T) {
+	tests := map[string]struct {
+		params  map[string]string
+		wantErr bool
+	}{
+		"valid": {
+			params: map[string]string{
+				"storage":                   "lvm",
+				"vgPattern":                 "^lvmvg.*$",
+				"thinProvision":             "yes",
+				"fsType":                    "xfs",
+				"scheduler":                 CapacityWeighted,
+				"allowedOverrides":          "vgpattern, thinProvision",
+				"lvNameTemplate":            "{{ .PVCNamespace }}-{{ .Hash }}",
+				"csi.storage.k8s.io/fstype": "ext4",
+			},
+		},
+		"volgroup only": {
+			params: map[string]string{"volgroup": "lvmvg"},
+		},
+		"missing vgpattern": {
+			params:  map[string]string{"shared": "yes"},
+			wantErr: true,
+		},
+		"invalid vgpattern": {
+			params:  map[string]string{"vgpattern": "lvmvg[0-9"},
+			wantErr: true,
+		},
+		"unknown param": {
+			params:  map[string]string{"volgroup": "lvmvg", "thinprovison": "yes"},
+			wantErr: true,
+		},
+		"invalid thinProvision": {
+			params:  map[string]string{"volgroup": "lvmvg", "thinProvision": "true"},
+			wantErr: true,
+		},
+		"invalid shared": {
+			params:  map[string]string{"volgroup": "lvmvg", "shared": "Yes"},
+			wantErr: true,
+		},
+		"invalid scheduler": {
+			params:  map[string]string{"volgroup": "lvmvg", "scheduler": "spaceweighted"},
+			wantErr: true,
+		},
+		"spread": {
+			params: map[string]string{"volgroup": "lvmvg", "spreadBy": "StatefulSet", "spreadPolicy": "Required"},
+		},
+		"invalid spreadBy": {
+			params:  map[string]string{"volgroup": "lvmvg", "spreadBy": "Label"},
+			wantErr: true,
+		},
+		"unknown override": {
+			params:  map[string]string{"volgroup": "lvmvg", "allowedOverrides": "vgpatern"},
+			wantErr: true,
+		},
+		"invalid lvNameTemplate": {
+			params:  map[string]string{"volgroup": "lvmvg", "lvNameTemplate": "{{ .PVCName }}"},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateStorageClassParams(tt.params)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestValidateSnapshotClassParams(t *testing.T) {
+	assert.NoError(t, ValidateSnapshotClassParams(map[string]string{"snapSize": "50%"}))
+	assert.NoError(t, ValidateSnapshotClassParams(map[string]string{
+		"csi.storage.k8s.io/snapshotter-secret-name": "secret",
+	}))
+	assert.Error(t, ValidateSnapshotClassParams(map[string]string{"snapSize": "150%"}))
+	assert.Error(t, ValidateSnapshotClassParams(map[string]string{"snapsise": "10Gi"}))
+}
+
+func TestWebhookHandler(t *testing.T) {
+	review := func(kind string, obj interface{}) *admissionv1.AdmissionReview {
+		raw, err := json.Marshal(obj)
+		assert.NoError(t, err)
+		return &admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:    "uid",
+				Kind:   metav1.GroupVersionKind{Kind: kind},
+				Object: runtime.RawExtension{Raw: raw},
+			},
+		}
+	}
+	tests := map[string]struct {
+		review  *admissionv1.AdmissionReview
+		allowed bool
+	}{
+		"valid storage class": {
+			review: review("StorageClass", map[string]interface{}{
+				"provisioner": "local.csi.openebs.io",
+				"parameters":  map[string]string{"volgroup": "lvmvg"},
+			}),
+			allowed: true,
+		},
+		"invalid storage class": {
+			review: review("StorageClass", map[string]interface{}{
+				"provisioner": "local.csi.openebs.io",
+				"parameters":  map[string]string{"volgroup": "lvmvg", "thinProvision": "true"},
+			}),
+		},
+		"storage class of another provisioner": {
+			review: review("StorageClass", map[string]interface{}{
+				"provisioner": "other.csi.io",
+				"parameters":  map[string]string{"thinProvision": "true"},
+			}),
+			allowed: true,
+		},
+		"invalid snapshot class": {
+			review: review("VolumeSnapshotClass", map[string]interface{}{
+				"driver":     "local.csi.openebs.io",
+				"parameters": map[string]string{"snapSize": "150%"},
+			}),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			body, err := json.Marshal(tt.review)
+			assert.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			webhookHandler("local.csi.openebs.io").ServeHTTP(rec,
+				httptest.NewRequest(http.MethodPost, WebhookValidatePath, bytes.NewReader(body)))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			got := &admissionv1.AdmissionReview{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), got))
+			assert.Equal(t, tt.review.Request.UID, got.Response.UID)
+			assert.Equal(t, tt.allowed, got.Response.Allowed)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	webhookHandler("local.csi.openebs.io").ServeHTTP(rec,
+		httptest.NewRequest(http.MethodPost, WebhookValidatePath, bytes.NewReader([]byte("{}"))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// writeTestCert writes a self-signed certificate with the
+// common name and its key to the cert and key files.
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	assert.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	assert.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func TestCertLoader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	loader := &certLoader{certFile: certFile, keyFile: keyFile}
+
+	commonName := func() string {
+		cert, err := loader.getCertificate(nil)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+
+	_, err := loader.getCertificate(nil)
+	assert.Error(t, err, "no certificate")
+
+	now := time.Now()
+	writeTestCert(t, certFile, keyFile, "first", now)
+	assert.Equal(t, "first", commonName())
+
+	// the renewed certificate is loaded.
+	writeTestCert(t, certFile, keyFile, "renewed", now.Add(time.Minute))
+	assert.Equal(t, "renewed", commonName())
+
+	// the previous certificate is served while the files are updated.
+	assert.NoError(t, os.WriteFile(keyFile, []byte("partial"), 0600))
+	assert.NoError(t, os.Chtimes(keyFile, now.Add(2*time.Minute), now.Add(2*time.Minute)))
+	assert.Equal(t, "renewed", commonName())
+	assert.NoError(t, os.Remove(certFile))
+	assert.Equal(t, "renewed", commonName())
+}
@@ -36,6 +36,10 @@ func (c *FakeLocalV1alpha1) LVMSnapshots(namespace string) v1alpha1.LVMSnapshotI
 	return &FakeLVMSnapshots{c, namespace}
 }
 
+func (c *FakeLocalV1alpha1) LVMSnapshotGroups(namespace string) v1alpha1.LVMSnapshotGroupInterface {
+	return &FakeLVMSnapshotGroups{c, namespace}
+}
+
 func (c *FakeLocalV1alpha1) LVMVolumes(namespace string) v1alpha1.LVMVolumeInterface {
 	return &FakeLVMVolumes{c, namespace}
 }
@@ -22,4 +22,6 @@ type LVMNodeExpansion interface{}
 
 type LVMSnapshotExpansion interface{}
 
+type LVMSnapshotGroupExpansion interface{}
+
 type LVMVolumeExpansion interface{}
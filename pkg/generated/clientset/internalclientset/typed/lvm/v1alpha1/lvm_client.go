@@ -30,6 +30,7 @@ type LocalV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	LVMNodesGetter
 	LVMSnapshotsGetter
+	LVMSnapshotGroupsGetter
 	LVMVolumesGetter
 }
 
@@ -46,6 +47,10 @@ func (c *LocalV1alpha1Client) LVMSnapshots(namespace string) LVMSnapshotInterfac
 	return newLVMSnapshots(c, namespace)
 }
 
+func (c *LocalV1alpha1Client) LVMSnapshotGroups(namespace string) LVMSnapshotGroupInterface {
+	return newLVMSnapshotGroups(c, namespace)
+}
+
 func (c *LocalV1alpha1Client) LVMVolumes(namespace string) LVMVolumeInterface {
 	return newLVMVolumes(c, namespace)
 }
@@ -57,6 +57,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Local().V1alpha1().LVMNodes().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("lvmsnapshots"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Local().V1alpha1().LVMSnapshots().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("lvmsnapshotgroups"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Local().V1alpha1().LVMSnapshotGroups().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("lvmvolumes"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Local().V1alpha1().LVMVolumes().Informer()}, nil
 
@@ -28,6 +28,8 @@ type Interface interface {
 	LVMNodes() LVMNodeInformer
 	// LVMSnapshots returns a LVMSnapshotInformer.
 	LVMSnapshots() LVMSnapshotInformer
+	// LVMSnapshotGroups returns a LVMSnapshotGroupInformer.
+	LVMSnapshotGroups() LVMSnapshotGroupInformer
 	// LVMVolumes returns a LVMVolumeInformer.
 	LVMVolumes() LVMVolumeInformer
 }
@@ -53,6 +55,11 @@ func (v *version) LVMSnapshots() LVMSnapshotInformer {
 	return &lVMSnapshotInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// LVMSnapshotGroups returns a LVMSnapshotGroupInformer.
+func (v *version) LVMSnapshotGroups() LVMSnapshotGroupInformer {
+	return &lVMSnapshotGroupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // LVMVolumes returns a LVMVolumeInformer.
 func (v *version) LVMVolumes() LVMVolumeInformer {
 	return &lVMVolumeInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -34,6 +34,14 @@ type LVMSnapshotListerExpansion interface{}
 // LVMSnapshotNamespaceLister.
 type LVMSnapshotNamespaceListerExpansion interface{}
 
+// LVMSnapshotGroupListerExpansion allows custom methods to be added to
+// LVMSnapshotGroupLister.
+type LVMSnapshotGroupListerExpansion interface{}
+
+// LVMSnapshotGroupNamespaceListerExpansion allows custom methods to be added to
+// LVMSnapshotGroupNamespaceLister.
+type LVMSnapshotGroupNamespaceListerExpansion interface{}
+
 // LVMVolumeListerExpansion allows custom methods to be added to
 // LVMVolumeLister.
 type LVMVolumeListerExpansion interface{}
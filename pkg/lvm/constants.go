@@ -53,6 +53,10 @@ const (
 	LVDataPercent     = "data_percent"
 	LVMetadataPercent = "metadata_percent"
 	LVSnapPercent     = "snap_percent"
+	LVDeviceOpen      = "lv_device_open"
+	LVMerging         = "lv_merging"
+	LVTime            = "lv_time"
+	LVOriginSize      = "origin_size"
 
 	PVName             = "pv_name"
 	PVUUID             = "pv_uuid"
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+)
+
+// Reasons of the events recorded for the failed operations
+// on the lvm volumes and snapshots.
+const (
+	// EventReasonInsufficientCapacity is recorded when the volume groups
+	// matching the volume do not have enough free space for it.
+	EventReasonInsufficientCapacity string = "InsufficientCapacity"
+	// EventReasonVGNotFound is recorded when no volume group
+	// on the node matches the volume.
+	EventReasonVGNotFound string = "VGNotFound"
+	// EventReasonLVCreateFailed is recorded when the logical
+	// volume can not be created.
+	EventReasonLVCreateFailed string = "LVCreateFailed"
+	// EventReasonResizeFailed is recorded when the volume can not be resized.
+	EventReasonResizeFailed string = "ResizeFailed"
+	// EventReasonSnapshotFailed is recorded when the snapshot can not be created.
+	EventReasonSnapshotFailed string = "SnapshotFailed"
+)
+
+// NewEventRecorder returns an event recorder recording the
+// events of the given component to the Kubernetes API.
+func NewEventRecorder(kubeClient kubernetes.Interface, component string) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component})
+}
+
+// VolumeErrorReason returns the reason of the event
+// recorded for the given volume provisioning error.
+func VolumeErrorReason(volErr *apis.VolumeError) string {
+	if volErr == nil {
+		return EventReasonLVCreateFailed
+	}
+	switch volErr.Code {
+	case apis.InsufficientCapacity:
+		return EventReasonInsufficientCapacity
+	case apis.VGNotFound:
+		return EventReasonVGNotFound
+	}
+	return EventReasonLVCreateFailed
+}
+
+// RecordVolumeEvent records the event on the LVMVolume
+// and on the PVC of the volume, if it is known.
+func RecordVolumeEvent(recorder record.EventRecorder, vol *apis.LVMVolume,
+	eventtype, reason, message string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Event(&corev1.ObjectReference{
+		Kind:       "LVMVolume",
+		APIVersion: apis.SchemeGroupVersion.String(),
+		Namespace:  vol.Namespace,
+		Name:       vol.Name,
+		UID:        vol.UID,
+	}, eventtype, reason, message)
+	recordPVCEvent(recorder, vol, eventtype, reason, message)
+}
+
+// RecordSnapshotEvent records the event on the LVMSnapshot and
+// on the PVC of its source volume, if it is known.
+func RecordSnapshotEvent(recorder record.EventRecorder, snap *apis.LVMSnapshot,
+	vol *apis.LVMVolume, eventtype, reason, message string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Event(&corev1.ObjectReference{
+		Kind:       "LVMSnapshot",
+		APIVersion: apis.SchemeGroupVersion.String(),
+		Namespace:  snap.Namespace,
+		Name:       snap.Name,
+		UID:        snap.UID,
+	}, eventtype, reason, message)
+	if vol != nil {
+		recordPVCEvent(recorder, vol, eventtype, reason, message)
+	}
+}
+
+// recordPVCEvent records the event on the PVC of the volume
+// as per the annotations set on the volume when it is created.
+func recordPVCEvent(recorder record.EventRecorder, vol *apis.LVMVolume,
+	eventtype, reason, message string) {
+	name, namespace := vol.Annotations[LVMPVCNameKey], vol.Annotations[LVMPVCNamespaceKey]
+	if name == "" || namespace == "" {
+		return
+	}
+	recorder.Event(&corev1.ObjectReference{
+		Kind:       "PersistentVolumeClaim",
+		APIVersion: "v1",
+		Namespace:  namespace,
+		Name:       name,
+	}, eventtype, reason, message)
+}
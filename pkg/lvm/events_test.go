@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	"strings"
+	"testing"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestVolumeErrorReason(t *testing.T) {
+	tests := map[apis.VolumeErrorCode]string{
+		apis.InsufficientCapacity: EventReasonInsufficientCapacity,
+		apis.VGNotFound:           EventReasonVGNotFound,
+		apis.Internal:             EventReasonLVCreateFailed,
+	}
+	for code, want := range tests {
+		if got := VolumeErrorReason(&apis.VolumeError{Code: code}); got != want {
+			t.Errorf("VolumeErrorReason(%s) = %s, want %s", code, got, want)
+		}
+	}
+	if got := VolumeErrorReason(nil); got != EventReasonLVCreateFailed {
+		t.Errorf("VolumeErrorReason(nil) = %s, want %s", got, EventReasonLVCreateFailed)
+	}
+}
+
+func TestRecordVolumeEvent(t *testing.T) {
+	vol := &apis.LVMVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1", Namespace: "openebs"},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	recorder.IncludeObject = true
+	RecordVolumeEvent(recorder, vol, corev1.EventTypeWarning, EventReasonVGNotFound, "no vg")
+	events := drainEvents(recorder)
+	if len(events) != 1 || !strings.Contains(events[0], "kind=LVMVolume") {
+		t.Errorf("expected an event on the volume only, got %v", events)
+	}
+
+	vol.Annotations = map[string]string{
+		LVMPVCNameKey:      "data",
+		LVMPVCNamespaceKey: "default",
+	}
+	RecordVolumeEvent(recorder, vol, corev1.EventTypeWarning, EventReasonVGNotFound, "no vg")
+	events = drainEvents(recorder)
+	if len(events) != 2 || !strings.Contains(events[1], "kind=PersistentVolumeClaim") {
+		t.Errorf("expected an event on the volume and the pvc, got %v", events)
+	}
+
+	snap := &apis.LVMSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapshot-1", Namespace: "openebs"},
+	}
+	RecordSnapshotEvent(recorder, snap, vol, corev1.EventTypeWarning, EventReasonSnapshotFailed, "failed")
+	events = drainEvents(recorder)
+	if len(events) != 2 || !strings.Contains(events[0], "kind=LVMSnapshot") ||
+		!strings.Contains(events[1], "kind=PersistentVolumeClaim") {
+		t.Errorf("expected an event on the snapshot and the pvc, got %v", events)
+	}
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2024 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	"fmt"
+	"strings"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+)
+
+// lv health status values as per the enum of lv_health_status
+const (
+	lvHealthPartial         = 1
+	lvHealthMismatchesExist = 3
+)
+
+// GetVolumeCondition returns the condition of the lvm volume as per the
+// state of its logical volume, its volume group and its thin pool given
+// in the lists of logical volumes and volume groups present on the node.
+func GetVolumeCondition(vol *apis.LVMVolume,
+	lvs []LogicalVolume, vgs []apis.VolumeGroup) *apis.VolumeCondition {
+	var problems []string
+
+	var lv *LogicalVolume
+	for i := range lvs {
+		if lvs[i].VGName == vol.Spec.VolGroup && lvs[i].Name == getLVName(vol) {
+			lv = &lvs[i]
+			break
+		}
+	}
+
+	if lv == nil {
+		problems = append(problems, fmt.Sprintf("logical volume %s/%s is missing",
+			vol.Spec.VolGroup, getLVName(vol)))
+	} else {
+		switch lv.HealthStatus {
+		case lvHealthPartial:
+			problems = append(problems, "logical volume is partial, some of its physical volumes are missing")
+		case lvHealthMismatchesExist:
+			problems = append(problems, "logical volume has mismatches between its raid images")
+		}
+
+		if lv.PoolName != "" {
+			for _, pool := range lvs {
+				if pool.VGName != lv.VGName || pool.Name != lv.PoolName {
+					continue
+				}
+				if pool.UsedSizePercent >= 100 {
+					problems = append(problems, fmt.Sprintf("thin pool %s data is full", pool.Name))
+				}
+				if pool.MetadataUsedPercent >= 100 {
+					problems = append(problems, fmt.Sprintf("thin pool %s metadata is full", pool.Name))
+				}
+				break
+			}
+		}
+	}
+
+	for _, vg := range vgs {
+		if vg.Name == vol.Spec.VolGroup && vg.MissingPVCount > 0 {
+			problems = append(problems, fmt.Sprintf("volume group %s has %d missing physical volumes",
+				vg.Name, vg.MissingPVCount))
+			break
+		}
+	}
+
+	if len(problems) == 0 {
+		return &apis.VolumeCondition{Abnormal: false, Message: "volume is healthy"}
+	}
+	return &apis.VolumeCondition{Abnormal: true, Message: strings.Join(problems, "; ")}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2024 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	"testing"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetVolumeCondition(t *testing.T) {
+	vol := &apis.LVMVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec:       apis.VolumeInfo{VolGroup: "lvmvg"},
+	}
+	thinLV := LogicalVolume{Name: "pvc-1", VGName: "lvmvg", PoolName: "lvmvg_thinpool"}
+	pool := LogicalVolume{Name: "lvmvg_thinpool", VGName: "lvmvg", SegType: LVThinPool}
+	vg := apis.VolumeGroup{Name: "lvmvg"}
+
+	tests := map[string]struct {
+		lvs      []LogicalVolume
+		vgs      []apis.VolumeGroup
+		abnormal bool
+	}{
+		"healthy volume": {
+			lvs: []LogicalVolume{thinLV, pool},
+			vgs: []apis.VolumeGroup{vg},
+		},
+		"missing logical volume": {
+			lvs:      []LogicalVolume{{Name: "pvc-1", VGName: "othervg"}},
+			vgs:      []apis.VolumeGroup{vg},
+			abnormal: true,
+		},
+		"partial logical volume": {
+			lvs:      []LogicalVolume{{Name: "pvc-1", VGName: "lvmvg", HealthStatus: lvHealthPartial}},
+			vgs:      []apis.VolumeGroup{vg},
+			abnormal: true,
+		},
+		"mismatches in logical volume": {
+			lvs:      []LogicalVolume{{Name: "pvc-1", VGName: "lvmvg", HealthStatus: lvHealthMismatchesExist}},
+			vgs:      []apis.VolumeGroup{vg},
+			abnormal: true,
+		},
+		"missing physical volumes": {
+			lvs:      []LogicalVolume{thinLV, pool},
+			vgs:      []apis.VolumeGroup{{Name: "lvmvg", MissingPVCount: 1}},
+			abnormal: true,
+		},
+		"thin pool data full": {
+			lvs: []LogicalVolume{thinLV, {Name: "lvmvg_thinpool", VGName: "lvmvg",
+				UsedSizePercent: 100}},
+			vgs:      []apis.VolumeGroup{vg},
+			abnormal: true,
+		},
+		"thin pool metadata full": {
+			lvs: []LogicalVolume{thinLV, {Name: "lvmvg_thinpool", VGName: "lvmvg",
+				MetadataUsedPercent: 100}},
+			vgs:      []apis.VolumeGroup{vg},
+			abnormal: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cond := GetVolumeCondition(vol, test.lvs, test.vgs)
+			if cond.Abnormal != test.abnormal {
+				t.Errorf("GetVolumeCondition() abnormal = %v, want %v, message %q",
+					cond.Abnormal, test.abnormal, cond.Message)
+			}
+		})
+	}
+}
@@ -17,8 +17,11 @@ package lvm
 import (
 	"testing"
 
+	"github.com/openebs/lib-csi/pkg/device/iolimit"
 	"github.com/openebs/lvm-localpv/pkg/driver/config"
 	"github.com/stretchr/testify/assert"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
 )
 
 func TestExtractingIoLimits(t *testing.T) {
@@ -69,3 +72,68 @@ func TestExtractingIoLimits(t *testing.T) {
 		}
 	}
 }
+
+func TestGetIOMax(t *testing.T) {
+	// the rates are set only once by SetIORateLimits
+	rwlock.Lock()
+	setValues(&config.Config{
+		RIopsLimitPerGB: &[]string{"lvmvg1:50"},
+		WIopsLimitPerGB: &[]string{"lvmvg1:70"},
+		RBpsLimitPerGB:  &[]string{"lvmvg1:1024"},
+		WBpsLimitPerGB:  &[]string{"lvmvg1:2048"},
+	})
+	rwlock.Unlock()
+	tests := map[string]struct {
+		limits   *apis.VolumeIOLimits
+		expected *iolimit.IOMax
+	}{
+		"volume group rates": {
+			expected: &iolimit.IOMax{Riops: 100, Wiops: 140, Rbps: 2048, Wbps: 4096},
+		},
+		"volume limits": {
+			limits:   &apis.VolumeIOLimits{ReadIOPS: 500, WriteIOPS: 200, ReadBPS: 1, WriteBPS: 2},
+			expected: &iolimit.IOMax{Riops: 500, Wiops: 200, Rbps: 1, Wbps: 2},
+		},
+		"some volume limits": {
+			limits:   &apis.VolumeIOLimits{WriteIOPS: 200},
+			expected: &iolimit.IOMax{Riops: 100, Wiops: 200, Rbps: 2048, Wbps: 4096},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vol := &apis.LVMVolume{
+				Spec: apis.VolumeInfo{Capacity: "2147483648", IOLimits: tt.limits},
+			}
+			ioMax, err := getIOMax(vol, "lvmvg1")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ioMax)
+		})
+	}
+}
+
+func TestGetPodUID(t *testing.T) {
+	tests := map[string]struct {
+		targetPath string
+		uid        string
+		ok         bool
+	}{
+		"filesystem": {
+			targetPath: "/var/lib/kubelet/pods/0c7ffa4d-0a3b-4a44-9d9a-4f6e4b1f3c2a/volumes/kubernetes.io~csi/pvc-1/mount",
+			uid:        "0c7ffa4d-0a3b-4a44-9d9a-4f6e4b1f3c2a",
+			ok:         true,
+		},
+		"block": {
+			targetPath: "/var/lib/kubelet/plugins/kubernetes.io/csi/volumeDevices/publish/pvc-1/0c7ffa4d-0a3b-4a44-9d9a-4f6e4b1f3c2a",
+			uid:        "0c7ffa4d-0a3b-4a44-9d9a-4f6e4b1f3c2a",
+			ok:         true,
+		},
+		"unknown": {targetPath: "/mnt/pvc-1"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			uid, ok := getPodUID(tt.targetPath)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.uid, uid)
+		})
+	}
+}
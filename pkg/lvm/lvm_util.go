@@ -25,7 +25,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	mnt "github.com/openebs/lib-csi/pkg/mount"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
@@ -43,6 +45,27 @@ const (
 
 	// BlockCleanerCommand is the command used to clean filesystem on the device
 	BlockCleanerCommand = "wipefs"
+
+	// BlockCopyCommand is the command used to copy the content of one
+	// device to another
+	BlockCopyCommand = "dd"
+
+	// BlockDeviceCommand is the command used to query the size of a device
+	BlockDeviceCommand = "blockdev"
+
+	// FSFreezeCommand is the command used to freeze and unfreeze
+	// the filesystem mounted on a device
+	FSFreezeCommand = "fsfreeze"
+
+	// DMSetupCommand is the command used to suspend and resume
+	// the device mapper devices of the logical volumes
+	DMSetupCommand = "dmsetup"
+
+	// copyBlockSize is the block size used while copying the content of one
+	// device to another and copyChunkBlocks is the number of blocks copied
+	// before the progress of the copy is reported.
+	copyBlockSize   = 4 * 1024 * 1024
+	copyChunkBlocks = 256
 )
 
 // lvm command related constants
@@ -50,10 +73,12 @@ const (
 	VGCreate = "vgcreate"
 	VGList   = "vgs"
 
-	LVCreate = "lvcreate"
-	LVRemove = "lvremove"
-	LVExtend = "lvextend"
-	LVList   = "lvs"
+	LVCreate  = "lvcreate"
+	LVRemove  = "lvremove"
+	LVExtend  = "lvextend"
+	LVChange  = "lvchange"
+	LVConvert = "lvconvert"
+	LVList    = "lvs"
 
 	PVList = "pvs"
 	PVScan = "pvscan"
@@ -212,14 +237,46 @@ func newExecError(output []byte, err error) error {
 	}
 }
 
+// transientErrors are the messages in the lvm command outputs of the
+// failures which may not occur if the command is retried.
+var transientErrors = []string{
+	"can't get lock",
+	"giving up waiting for lock",
+	"resource temporarily unavailable",
+	"device or resource busy",
+}
+
+// IsTransientError checks if the error is returned by an lvm command
+// which may succeed if retried, like when the lvm lock is held by
+// another command.
+func IsTransientError(err error) bool {
+	execErr, ok := err.(*ExecError)
+	if !ok {
+		return false
+	}
+	output := strings.ToLower(string(execErr.Output))
+	for _, msg := range transientErrors {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetThinPoolName returns the name of the thin pool from which the thin
+// provisioned volumes of the given volume group are allocated
+func GetThinPoolName(vgName string) string {
+	return vgName + "_thinpool"
+}
+
 // builldLVMCreateArgs returns lvcreate command for the volume
 func buildLVMCreateArgs(vol *apis.LVMVolume) []string {
 	var LVMVolArg []string
 
-	volume := vol.Name
+	volume := getLVName(vol)
 	size := vol.Spec.Capacity + "b"
 	// thinpool name required for thinProvision volumes
-	pool := vol.Spec.VolGroup + "_thinpool"
+	pool := GetThinPoolName(vol.Spec.VolGroup)
 
 	if len(vol.Spec.Capacity) != 0 {
 		// check if thin pool exists for given volumegroup requested thin volume
@@ -254,13 +311,19 @@ func buildLVMCreateArgs(vol *apis.LVMVolume) []string {
 func buildLVMDestroyArgs(vol *apis.LVMVolume) []string {
 	var LVMVolArg []string
 
-	dev := DevPath + vol.Spec.VolGroup + "/" + vol.Name
+	dev := DevPath + vol.Spec.VolGroup + "/" + getLVName(vol)
 
 	LVMVolArg = append(LVMVolArg, "-y", dev)
 
 	return LVMVolArg
 }
 
+// runCommand runs the command, it is replaced in the tests.
+var runCommand = (*exec.Cmd).Run
+
+// getMounts returns the mount points of the device, it is replaced in the tests.
+var getMounts = mnt.GetMounts
+
 // RunCommandSplit is a wrapper function to run a command and receive its
 // STDERR and STDOUT streams in separate []byte vars.
 func RunCommandSplit(command string, args ...string) ([]byte, []byte, error) {
@@ -270,7 +333,7 @@ func RunCommandSplit(command string, args ...string) ([]byte, []byte, error) {
 	cmd := exec.Command(command, args...)
 	cmd.Stdout = &cmdStdout
 	cmd.Stderr = &cmdStderr
-	err := cmd.Run()
+	err := runCommand(cmd)
 
 	output := cmdStdout.Bytes()
 	error_output := cmdStderr.Bytes()
@@ -284,7 +347,7 @@ func RunCommandSplit(command string, args ...string) ([]byte, []byte, error) {
 
 // CreateVolume creates the lvm volume
 func CreateVolume(vol *apis.LVMVolume) error {
-	volume := vol.Spec.VolGroup + "/" + vol.Name
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
 
 	volExists, err := CheckVolumeExists(vol)
 	if err != nil {
@@ -317,7 +380,7 @@ func DestroyVolume(vol *apis.LVMVolume) error {
 		return nil
 	}
 
-	volume := vol.Spec.VolGroup + "/" + vol.Name
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
 
 	volExists, err := CheckVolumeExists(vol)
 	if err != nil {
@@ -369,7 +432,7 @@ func GetVolumeDevPath(vol *apis.LVMVolume) (string, error) {
 	// and uses single hiphen to separate volume group from volume
 	vg := strings.Replace(vol.Spec.VolGroup, "-", "--", -1)
 
-	lv := strings.Replace(vol.Name, "-", "--", -1)
+	lv := strings.Replace(getLVName(vol), "-", "--", -1)
 	dev := DevMapperPath + vg + "-" + lv
 
 	return dev, nil
@@ -379,7 +442,7 @@ func GetVolumeDevPath(vol *apis.LVMVolume) (string, error) {
 func buildVolumeResizeArgs(vol *apis.LVMVolume, resizefs bool) []string {
 	var LVMVolArg []string
 
-	dev := DevPath + vol.Spec.VolGroup + "/" + vol.Name
+	dev := DevPath + vol.Spec.VolGroup + "/" + getLVName(vol)
 	size := vol.Spec.Capacity + "b"
 
 	LVMVolArg = append(LVMVolArg, dev, "-L", size)
@@ -422,7 +485,7 @@ func ResizeLVMVolume(vol *apis.LVMVolume, resizefs bool) error {
 		}
 	}
 
-	volume := vol.Spec.VolGroup + "/" + vol.Name
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
 
 	args := buildVolumeResizeArgs(vol, resizefs)
 	out, _, err := RunCommandSplit(LVExtend, args...)
@@ -436,9 +499,77 @@ func ResizeLVMVolume(vol *apis.LVMVolume, resizefs bool) error {
 	return err
 }
 
+// getLVAttribute returns the value of the given reporting field of
+// the logical volume.
+func getLVAttribute(lvName string, field string) (string, error) {
+	raw, _, err := RunCommandSplit(LVList, lvName, "--noheadings", "-o", field)
+	if err != nil {
+		return "", errors.Wrapf(
+			err,
+			"could not get %v of volume %v output: %s",
+			field,
+			lvName,
+			string(raw),
+		)
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// changeLV runs lvchange on the logical volume with the given args.
+func changeLV(lvName string, args ...string) error {
+	args = append(args, lvName)
+	out, _, err := RunCommandSplit(LVChange, args...)
+	if err != nil {
+		klog.Errorf(
+			"lvm: could not change the volume %v cmd %v error: %s", lvName, args, string(out),
+		)
+		return newExecError(out, err)
+	}
+	klog.Infof("lvm: changed the volume %v with %v", lvName, args)
+	return nil
+}
+
+// ModifyLVMVolume applies the mutable properties of the lvm volume, i.e.
+// the permission of the logical volume and the behaviour of its thin pool
+// when it is full, if they differ from the current ones.
+func ModifyLVMVolume(vol *apis.LVMVolume) error {
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
+
+	if vol.Spec.Permission != "" {
+		perm, err := getLVAttribute(volume, LVPermissions)
+		if err != nil {
+			return err
+		}
+		if (vol.Spec.Permission == "r") != (perm == "read-only") {
+			if err = changeLV(volume, "--permission", vol.Spec.Permission); err != nil {
+				return err
+			}
+		}
+	}
+
+	if vol.Spec.ErrorWhenFull != "" && strings.TrimSpace(vol.Spec.ThinProvision) == YES {
+		pool := vol.Spec.VolGroup + "/" + GetThinPoolName(vol.Spec.VolGroup)
+		whenFull, err := getLVAttribute(pool, LVWhenFull)
+		if err != nil {
+			return err
+		}
+		errorWhenFull := vol.Spec.ErrorWhenFull == YES
+		if errorWhenFull != (whenFull == "error") {
+			flag := "n"
+			if errorWhenFull {
+				flag = "y"
+			}
+			if err = changeLV(pool, "--errorwhenfull", flag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // getLVSize will return current LVM volume size in bytes
 func getLVSize(vol *apis.LVMVolume) (uint64, error) {
-	lvmVolumeName := vol.Spec.VolGroup + "/" + vol.Name
+	lvmVolumeName := vol.Spec.VolGroup + "/" + getLVName(vol)
 
 	args := []string{
 		lvmVolumeName,
@@ -466,11 +597,10 @@ func getLVSize(vol *apis.LVMVolume) (uint64, error) {
 	return volSize, nil
 }
 
-func buildLVMSnapCreateArgs(snap *apis.LVMSnapshot) []string {
+func buildLVMSnapCreateArgs(vol *apis.LVMVolume, snap *apis.LVMSnapshot) []string {
 	var LVMSnapArg []string
 
-	volName := snap.Labels[LVMVolKey]
-	volPath := DevPath + snap.Spec.VolGroup + "/" + volName
+	volPath := DevPath + snap.Spec.VolGroup + "/" + getLVName(vol)
 	size := snap.Spec.SnapSize + "b"
 
 	LVMSnapArg = append(LVMSnapArg,
@@ -504,14 +634,14 @@ func buildLVMSnapDestroyArgs(snap *apis.LVMSnapshot) []string {
 	return LVMSnapArg
 }
 
-// CreateSnapshot creates the lvm volume snapshot
-func CreateSnapshot(snap *apis.LVMSnapshot) error {
+// CreateSnapshot creates the lvm volume snapshot of the given volume
+func CreateSnapshot(vol *apis.LVMVolume, snap *apis.LVMSnapshot) error {
 
-	volume := snap.Labels[LVMVolKey]
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
 
 	snapVolume := snap.Spec.VolGroup + "/" + getLVMSnapName(snap.Name)
 
-	args := buildLVMSnapCreateArgs(snap)
+	args := buildLVMSnapCreateArgs(vol, snap)
 	out, _, err := RunCommandSplit(LVCreate, args...)
 
 	if err != nil {
@@ -524,6 +654,212 @@ func CreateSnapshot(snap *apis.LVMSnapshot) error {
 
 }
 
+// freezeVolumes freezes the mounted filesystems of the given lvm volumes,
+// so that no new writes reach the devices until they are thawed. The
+// volumes which do not have a mounted filesystem, e.g. the block volumes
+// used raw by the pods, get their device mapper device suspended instead.
+// It returns the func which thaws all the frozen filesystems and resumes
+// all the suspended devices.
+func freezeVolumes(vols []*apis.LVMVolume) (func(), error) {
+	var frozen, suspended []string
+
+	thaw := func() {
+		for _, path := range frozen {
+			out, _, err := RunCommandSplit(FSFreezeCommand, "--unfreeze", path)
+			if err != nil {
+				klog.Errorf("lvm: could not unfreeze filesystem at %s error: %s", path, string(out))
+				continue
+			}
+			klog.Infof("lvm: unfroze filesystem at %s", path)
+		}
+		for _, device := range suspended {
+			out, _, err := RunCommandSplit(DMSetupCommand, "resume", device)
+			if err != nil {
+				klog.Errorf("lvm: could not resume device %s error: %s", device, string(out))
+				continue
+			}
+			klog.Infof("lvm: resumed device %s", device)
+		}
+	}
+
+	for _, vol := range vols {
+		devicePath, err := GetVolumeDevPath(vol)
+		if err != nil {
+			thaw()
+			return nil, err
+		}
+
+		mounts, err := getMounts(devicePath)
+		if err != nil {
+			thaw()
+			return nil, errors.Wrapf(err, "failed to get mounts of volume %s", vol.Name)
+		}
+
+		if len(mounts) == 0 {
+			// the pending IOs are flushed before the device is suspended
+			device := strings.TrimPrefix(devicePath, DevMapperPath)
+			out, _, err := RunCommandSplit(DMSetupCommand, "suspend", device)
+			if err != nil {
+				klog.Errorf("lvm: could not suspend device %s error: %s", device, string(out))
+				thaw()
+				return nil, err
+			}
+			klog.Infof("lvm: suspended device %s of volume %s", device, vol.Name)
+			suspended = append(suspended, device)
+			continue
+		}
+
+		// all the mounts of the device share the same filesystem,
+		// freezing it through any of them is enough
+		out, _, err := RunCommandSplit(FSFreezeCommand, "--freeze", mounts[0])
+		if err != nil {
+			klog.Errorf("lvm: could not freeze filesystem at %s error: %s", mounts[0], string(out))
+			thaw()
+			return nil, err
+		}
+		klog.Infof("lvm: froze filesystem of volume %s at %s", vol.Name, mounts[0])
+		frozen = append(frozen, mounts[0])
+	}
+
+	return thaw, nil
+}
+
+// CreateSnapshotGroup creates the snapshots of a group of lvm volumes at the
+// same point in time. The filesystems of all the volumes, or the devices of
+// the volumes without a filesystem mounted, are frozen before the first
+// snapshot is taken and thawed after the last one, so that the snapshots
+// together capture a crash consistent state. The snapshots which
+// already exist are skipped and, if any of them can not be created, all the
+// snapshots of the group are removed.
+func CreateSnapshotGroup(vols []*apis.LVMVolume, snaps []*apis.LVMSnapshot) error {
+	thaw, err := freezeVolumes(vols)
+	if err != nil {
+		return err
+	}
+	defer thaw()
+
+	volumes := make(map[string]*apis.LVMVolume, len(vols))
+	for _, vol := range vols {
+		volumes[vol.Name] = vol
+	}
+
+	for _, snap := range snaps {
+		if ok, _ := isSnapshotExists(snap.Spec.VolGroup, getLVMSnapName(snap.Name)); ok {
+			continue
+		}
+
+		vol, ok := volumes[snap.Labels[LVMVolKey]]
+		if !ok {
+			err = fmt.Errorf("volume %s of snapshot %s is not a member of the group",
+				snap.Labels[LVMVolKey], snap.Name)
+		} else {
+			err = CreateSnapshot(vol, snap)
+		}
+		if err != nil {
+			for _, s := range snaps {
+				if derr := DestroySnapshot(s); derr != nil {
+					klog.Errorf("lvm: could not cleanup snapshot %s error: %v", s.Name, derr)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeSnapshot starts merging the snapshot into its origin volume, which
+// reverts the content of the volume to the one captured by the snapshot.
+// The merge runs in the background and LVM removes the snapshot once it
+// completes. If the volume is in use, LVM defers the merge until the next
+// activation of the volume.
+func MergeSnapshot(vol *apis.LVMVolume, snap *apis.LVMSnapshot) error {
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
+	snapVolume := snap.Spec.VolGroup + "/" + getLVMSnapName(snap.Name)
+
+	merging, err := getLVAttribute(snapVolume, LVMerging)
+	if err != nil {
+		return err
+	}
+	if merging != "" {
+		klog.Infof("lvm: snapshot %s is already being merged into %s", snapVolume, volume)
+		return nil
+	}
+
+	args := []string{"--merge", "--background", snapVolume}
+	out, _, err := RunCommandSplit(LVConvert, args...)
+	if err != nil {
+		klog.Errorf("lvm: could not merge snapshot %s cmd %v error: %s", snapVolume, args, string(out))
+		return newExecError(out, err)
+	}
+
+	klog.Infof("lvm: started merging snapshot %s into %s", snapVolume, volume)
+	return nil
+}
+
+// IsVolumeOpen checks if the device of the lvm volume is open, i.e. the
+// volume is mounted or in use as a raw block device.
+func IsVolumeOpen(vol *apis.LVMVolume) (bool, error) {
+	open, err := getLVAttribute(vol.Spec.VolGroup+"/"+getLVName(vol), LVDeviceOpen)
+	if err != nil {
+		return false, err
+	}
+	return open != "", nil
+}
+
+// IsSnapshotMerged checks if the snapshot has been merged into its origin
+// volume, i.e. the snapshot logical volume does not exist anymore.
+func IsSnapshotMerged(snap *apis.LVMSnapshot) (bool, error) {
+	snapName := getLVMSnapName(snap.Name)
+	out, _, err := RunCommandSplit(LVList, snap.Spec.VolGroup,
+		"--noheadings", "-o", LVName, "--select", LVName+"="+snapName)
+	if err != nil {
+		klog.Errorf("lvm: could not list snapshot %s/%s error: %s", snap.Spec.VolGroup, snapName, string(out))
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == "", nil
+}
+
+// GetSnapshotInfo returns the creation time of the snapshot logical
+// volume and the size in bytes of its origin volume.
+func GetSnapshotInfo(snap *apis.LVMSnapshot) (time.Time, int64, error) {
+	snapVolume := snap.Spec.VolGroup + "/" + getLVMSnapName(snap.Name)
+
+	// report the creation time as seconds since the epoch
+	args := []string{
+		snapVolume,
+		"--noheadings",
+		"--units", "b",
+		"--nosuffix",
+		"--separator", "|",
+		"--config", `report/time_format="%s"`,
+		"-o", LVTime + "," + LVOriginSize,
+	}
+	out, _, err := RunCommandSplit(LVList, args...)
+	if err != nil {
+		klog.Errorf("lvm: could not get info of snapshot %s error: %s", snapVolume, string(out))
+		return time.Time{}, 0, err
+	}
+	return parseSnapshotInfo(string(out))
+}
+
+// parseSnapshotInfo parses the lv_time and origin_size fields
+// reported by lvs for a snapshot logical volume.
+func parseSnapshotInfo(raw string) (time.Time, int64, error) {
+	fields := strings.Split(strings.TrimSpace(raw), "|")
+	if len(fields) != 2 {
+		return time.Time{}, 0, fmt.Errorf("invalid snapshot info %q", raw)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errors.Wrapf(err, "invalid %s %q", LVTime, fields[0])
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errors.Wrapf(err, "invalid %s %q", LVOriginSize, fields[1])
+	}
+	return time.Unix(seconds, 0), size, nil
+}
+
 // DestroySnapshot deletes the lvm volume snapshot
 func DestroySnapshot(snap *apis.LVMSnapshot) error {
 	snapVolume := snap.Spec.VolGroup + "/" + getLVMSnapName(snap.Name)
@@ -552,6 +888,164 @@ func DestroySnapshot(snap *apis.LVMSnapshot) error {
 
 }
 
+// buildThinCloneArgs returns lvcreate command to create a writable thin
+// snapshot of the given source logical volume.
+func buildThinCloneArgs(vol *apis.LVMVolume, srcPath string) []string {
+	var LVMVolArg []string
+
+	LVMVolArg = append(LVMVolArg,
+		"--snapshot",
+		"--name", getLVName(vol),
+		// thin snapshots are skipped during activation by default,
+		// the clone needs to be active to be used as a volume.
+		"--setactivationskip", "n",
+		// source snapshot might be read-only, the clone must be writable.
+		"--permission", "rw",
+		srcPath,
+	)
+	return LVMVolArg
+}
+
+// buildBlockCopyArgs returns dd command to copy count blocks of the
+// source device to the volume starting at the given block offset.
+func buildBlockCopyArgs(vol *apis.LVMVolume, srcPath string, offset, count uint64) []string {
+	dev := DevPath + vol.Spec.VolGroup + "/" + getLVName(vol)
+
+	return []string{
+		"if=" + srcPath,
+		"of=" + dev,
+		"bs=" + strconv.Itoa(copyBlockSize),
+		"skip=" + strconv.FormatUint(offset, 10),
+		"seek=" + strconv.FormatUint(offset, 10),
+		"count=" + strconv.FormatUint(count, 10),
+		"iflag=fullblock",
+		"conv=fsync",
+		"status=none",
+	}
+}
+
+// getDeviceSize returns the size of the given block device in bytes.
+func getDeviceSize(devPath string) (uint64, error) {
+	raw, _, err := RunCommandSplit(BlockDeviceCommand, "--getsize64", devPath)
+	if err != nil {
+		return 0, errors.Wrapf(
+			err,
+			"could not get size of device %v output: %s",
+			devPath,
+			string(raw),
+		)
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+}
+
+// createThinClone creates the volume as a writable thin snapshot of the
+// source logical volume. It is extended afterwards if the requested
+// capacity is more than the size of the source.
+func createThinClone(vol *apis.LVMVolume, srcPath string, progress func(int32)) error {
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
+
+	volExists, err := CheckVolumeExists(vol)
+	if err != nil {
+		return err
+	}
+	if !volExists {
+		args := buildThinCloneArgs(vol, srcPath)
+		out, _, err := RunCommandSplit(LVCreate, args...)
+		if err != nil {
+			err = newExecError(out, err)
+			klog.Errorf(
+				"lvm: could not create volume %v from %v cmd %v error: %s", volume, srcPath, args, string(out),
+			)
+			return err
+		}
+		klog.Infof("lvm: created volume %s from %s", volume, srcPath)
+	}
+
+	if err = ResizeLVMVolume(vol, false); err != nil {
+		return err
+	}
+	progress(100)
+	return nil
+}
+
+// copyVolumeContent creates the volume and copies the whole content of
+// the source device into it in chunks, reporting the percentage copied
+// after each chunk. The copy is repeated every time the function is
+// called, as there is no way to find out if the previous copy has
+// been completed.
+func copyVolumeContent(vol *apis.LVMVolume, srcPath string, progress func(int32)) error {
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
+
+	if err := CreateVolume(vol); err != nil {
+		return err
+	}
+
+	size, err := getDeviceSize(srcPath)
+	if err != nil {
+		return err
+	}
+	blocks := (size + copyBlockSize - 1) / copyBlockSize
+
+	for offset := uint64(0); offset < blocks; offset += copyChunkBlocks {
+		args := buildBlockCopyArgs(vol, srcPath, offset, copyChunkBlocks)
+		out, _, err := RunCommandSplit(BlockCopyCommand, args...)
+		if err != nil {
+			err = newExecError(out, err)
+			klog.Errorf(
+				"lvm: could not copy %v to volume %v cmd %v error: %s", srcPath, volume, args, string(out),
+			)
+			return err
+		}
+
+		copied := offset + copyChunkBlocks
+		if copied > blocks {
+			copied = blocks
+		}
+		progress(int32(copied * 100 / blocks))
+	}
+	klog.Infof("lvm: copied %s to volume %s", srcPath, volume)
+	progress(100)
+	return nil
+}
+
+// CreateVolumeFromSnapshot creates the lvm volume having the content of
+// the given snapshot. For thin provisioned volumes, the volume is a thin
+// snapshot of the snapshot, otherwise the snapshot is copied to a newly
+// created volume. The progress callback is invoked with the percentage
+// of the content copied so far.
+func CreateVolumeFromSnapshot(vol *apis.LVMVolume, snap *apis.LVMSnapshot, progress func(int32)) error {
+	snapPath := DevPath + snap.Spec.VolGroup + "/" + getLVMSnapName(snap.Name)
+
+	if strings.TrimSpace(vol.Spec.ThinProvision) == YES {
+		return createThinClone(vol, snapPath, progress)
+	}
+	return copyVolumeContent(vol, snapPath, progress)
+}
+
+// CreateVolumeClone creates the lvm volume having the content of the given
+// source volume. For thin provisioned volumes, the clone is a writable thin
+// snapshot of the source, otherwise the source is copied block by block to
+// a newly created volume. The block copy reads the source while it may be in
+// use, so the clone is only as consistent as the source at that time.
+func CreateVolumeClone(vol *apis.LVMVolume, srcVol *apis.LVMVolume, progress func(int32)) error {
+	srcPath := DevPath + srcVol.Spec.VolGroup + "/" + getLVName(srcVol)
+
+	if strings.TrimSpace(vol.Spec.ThinProvision) == YES {
+		return createThinClone(vol, srcPath, progress)
+	}
+	return copyVolumeContent(vol, srcPath, progress)
+}
+
+// getLVName returns the name of the logical volume of the given volume,
+// which is the name of the LVMVolume unless it has been set explicitly.
+func getLVName(vol *apis.LVMVolume) string {
+	if vol.Spec.LVName != "" {
+		return vol.Spec.LVName
+	}
+	return vol.Name
+}
+
 // getSnapName is used to remove the snapshot prefix from the snapname. since names starting
 // with "snapshot" are reserved in lvm2
 func getLVMSnapName(snapName string) string {
@@ -688,6 +1182,67 @@ func ListLVMVolumeGroup(reloadCache bool) ([]apis.VolumeGroup, error) {
 	return decodeVgsJSON(output)
 }
 
+// SetThinPools sets the thin pools present in the given volume groups
+// using the given logical volumes. The virtual size of a thin pool is the
+// sum of the sizes of all the thin volumes and snapshots allocated from it.
+func SetThinPools(vgs []apis.VolumeGroup, lvs []LogicalVolume) {
+	for i := range vgs {
+		var pools []apis.ThinPool
+		for _, lv := range lvs {
+			if lv.VGName != vgs[i].Name || lv.SegType != LVThinPool {
+				continue
+			}
+
+			var virtualSize int64
+			for _, thinLV := range lvs {
+				if thinLV.VGName == lv.VGName && thinLV.PoolName == lv.Name {
+					virtualSize += thinLV.Size
+				}
+			}
+
+			used := int64(float64(lv.Size) * lv.UsedSizePercent / 100)
+			metadataUsed := int64(float64(lv.MetadataSize) * lv.MetadataUsedPercent / 100)
+			pools = append(pools, apis.ThinPool{
+				Name:         lv.Name,
+				Size:         *resource.NewQuantity(lv.Size, resource.BinarySI),
+				Free:         *resource.NewQuantity(lv.Size-used, resource.BinarySI),
+				VirtualSize:  *resource.NewQuantity(virtualSize, resource.BinarySI),
+				MetadataSize: *resource.NewQuantity(lv.MetadataSize, resource.BinarySI),
+				MetadataFree: *resource.NewQuantity(lv.MetadataSize-metadataUsed, resource.BinarySI),
+			})
+		}
+		vgs[i].ThinPools = pools
+	}
+}
+
+// ThinPoolUsage returns the percentage of the data or of the metadata of
+// the thin pool which is used, whichever is the highest. The metadata
+// usage is not known for the thin pools reported by older node agents.
+func ThinPoolUsage(pool *apis.ThinPool) float64 {
+	var usage float64
+	if size := pool.Size.Value(); size > 0 {
+		usage = float64(size-pool.Free.Value()) * 100 / float64(size)
+	}
+	if size := pool.MetadataSize.Value(); size > 0 {
+		if metadataUsage := float64(size-pool.MetadataFree.Value()) * 100 / float64(size); metadataUsage > usage {
+			usage = metadataUsage
+		}
+	}
+	return usage
+}
+
+// GetThinPool returns the thin pool from which the thin provisioned
+// volumes of the volume group are allocated, nil if it does not exist yet.
+func GetThinPool(vg *apis.VolumeGroup) *apis.ThinPool {
+	poolName := GetThinPoolName(vg.Name)
+	for i := range vg.ThinPools {
+		if vg.ThinPools[i].Name == poolName {
+			return &vg.ThinPools[i]
+		}
+	}
+	return nil
+}
+
 // Function to get LVM Logical volume device
 // It returns LVM logical volume device(dm-*).
 // This is used as a label in metrics(lvm_lv_total_size) which helps us to map lv_name to device.
@@ -1078,7 +1633,7 @@ func getThinPoolSize(vgname, volsize string) string {
 
 // removeVolumeFilesystem will erases the filesystem signature from lvm volume
 func removeVolumeFilesystem(lvmVolume *apis.LVMVolume) error {
-	devicePath := filepath.Join(DevPath, lvmVolume.Spec.VolGroup, lvmVolume.Name)
+	devicePath := filepath.Join(DevPath, lvmVolume.Spec.VolGroup, getLVName(lvmVolume))
 
 	// wipefs erases the filesystem signature from the lvm volume
 	// -a    wipe all magic strings
@@ -17,8 +17,16 @@ limitations under the License.
 package lvm
 
 import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
 	"reflect"
+	"strings"
 	"testing"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
@@ -108,3 +116,270 @@ func Test_parseLogicalVolume(t *testing.T) {
 		})
 	}
 }
+
+func TestSetThinPools(t *testing.T) {
+	pool := LogicalVolume{Name: "lvmvg_thinpool", VGName: "lvmvg", SegType: LVThinPool,
+		Size: 10737418240, UsedSizePercent: 25, MetadataSize: 8388608, MetadataUsedPercent: 50}
+	lvs := []LogicalVolume{
+		pool,
+		{Name: "pvc-1", VGName: "lvmvg", SegType: "thin", PoolName: "lvmvg_thinpool", Size: 8589934592},
+		{Name: "pvc-2", VGName: "lvmvg", SegType: "thin", PoolName: "lvmvg_thinpool", Size: 4294967296},
+		{Name: "pvc-3", VGName: "lvmvg", SegType: "linear", Size: 1073741824},
+		{Name: "lvmvg_thinpool", VGName: "othervg", SegType: LVThinPool, Size: 1073741824},
+	}
+	vgs := []apis.VolumeGroup{{Name: "lvmvg"}, {Name: "emptyvg"}}
+
+	SetThinPools(vgs, lvs)
+
+	if len(vgs[0].ThinPools) != 1 {
+		t.Fatalf("SetThinPools() got %d thin pools for lvmvg, want 1", len(vgs[0].ThinPools))
+	}
+	got := vgs[0].ThinPools[0]
+	if got.Name != "lvmvg_thinpool" {
+		t.Errorf("SetThinPools() got name %s, want lvmvg_thinpool", got.Name)
+	}
+	if got.Size.Value() != 10737418240 {
+		t.Errorf("SetThinPools() got size %d, want 10737418240", got.Size.Value())
+	}
+	if got.Free.Value() != 8053063680 {
+		t.Errorf("SetThinPools() got free %d, want 8053063680", got.Free.Value())
+	}
+	if got.VirtualSize.Value() != 12884901888 {
+		t.Errorf("SetThinPools() got virtual size %d, want 12884901888", got.VirtualSize.Value())
+	}
+	if got.MetadataSize.Value() != 8388608 || got.MetadataFree.Value() != 4194304 {
+		t.Errorf("SetThinPools() got metadata size %d free %d, want 8388608 4194304",
+			got.MetadataSize.Value(), got.MetadataFree.Value())
+	}
+	if len(vgs[1].ThinPools) != 0 {
+		t.Errorf("SetThinPools() got %d thin pools for emptyvg, want 0", len(vgs[1].ThinPools))
+	}
+	if usage := ThinPoolUsage(&got); usage != 50 {
+		t.Errorf("ThinPoolUsage() got %v, want 50", usage)
+	}
+}
+
+func Test_parseSnapshotInfo(t *testing.T) {
+	tests := map[string]struct {
+		raw     string
+		time    int64
+		size    int64
+		wantErr bool
+	}{
+		"valid info":         {raw: "  1614852672|5368709120\n", time: 1614852672, size: 5368709120},
+		"missing field":      {raw: "  1614852672\n", wantErr: true},
+		"invalid time":       {raw: "  2021-03-04 10:11:12 +0000|5368709120\n", wantErr: true},
+		"invalid originsize": {raw: "  1614852672|\n", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotTime, gotSize, err := parseSnapshotInfo(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSnapshotInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if gotTime.Unix() != tt.time {
+				t.Errorf("parseSnapshotInfo() got time %d, want %d", gotTime.Unix(), tt.time)
+			}
+			if gotSize != tt.size {
+				t.Errorf("parseSnapshotInfo() got size %d, want %d", gotSize, tt.size)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"lock error": {
+			err:  newExecError([]byte("  Giving up waiting for lock.\n  Can't get lock for lvmvg\n"), errors.New("exit status 5")),
+			want: true,
+		},
+		"busy device": {
+			err:  newExecError([]byte("  Logical volume lvmvg/pvc-1 in use: Device or resource busy\n"), errors.New("exit status 5")),
+			want: true,
+		},
+		"insufficient space": {
+			err:  newExecError([]byte("  Volume group \"lvmvg\" has insufficient free space (255 extents): 256 required.\n"), errors.New("exit status 5")),
+			want: false,
+		},
+		"not an exec error": {
+			err:  errors.New("resource temporarily unavailable"),
+			want: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsTransientError(tt.err); got != tt.want {
+				t.Errorf("IsTransientError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeLVM runs the lvm, fsfreeze and dmsetup commands against an in
+// memory set of snapshots, recording the commands changing the state.
+type fakeLVM struct {
+	snaps       map[string]bool
+	failCreate  string
+	failFreeze  string
+	failSuspend string
+	calls       []string
+}
+
+func (f *fakeLVM) run(cmd *exec.Cmd) error {
+	args := cmd.Args
+	switch args[0] {
+	case "lvs":
+		name := path.Base(args[1])
+		if !f.snaps[name] {
+			return errors.New("exit status 5")
+		}
+		fmt.Fprintf(cmd.Stdout, "  %s\n", name)
+	case LVCreate:
+		name := args[3]
+		f.calls = append(f.calls, "lvcreate "+name)
+		if name == f.failCreate {
+			return errors.New("exit status 5")
+		}
+		f.snaps[name] = true
+	case LVRemove:
+		name := path.Base(args[2])
+		f.calls = append(f.calls, "lvremove "+name)
+		delete(f.snaps, name)
+	case FSFreezeCommand:
+		f.calls = append(f.calls, strings.TrimPrefix(args[1], "--")+" "+args[2])
+		if args[1] == "--freeze" && args[2] == f.failFreeze {
+			return errors.New("exit status 1")
+		}
+	case DMSetupCommand:
+		f.calls = append(f.calls, args[1]+" "+args[2])
+		if args[1] == "suspend" && args[2] == f.failSuspend {
+			return errors.New("exit status 1")
+		}
+	default:
+		return fmt.Errorf("unexpected command %v", args)
+	}
+	return nil
+}
+
+func TestCreateSnapshotGroup(t *testing.T) {
+	var vols []*apis.LVMVolume
+	var snaps []*apis.LVMSnapshot
+	mounts := map[string][]string{}
+	for _, name := range []string{"pvc-a", "pvc-b", "pvc-c"} {
+		vol := &apis.LVMVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       apis.VolumeInfo{VolGroup: "lvmvg"},
+		}
+		vols = append(vols, vol)
+		snaps = append(snaps, &apis.LVMSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "group-" + name,
+				Labels: map[string]string{LVMVolKey: name},
+			},
+			Spec: apis.LVMSnapshotSpec{VolGroup: "lvmvg"},
+		})
+		// pvc-c is not mounted, as a block volume
+		if name != "pvc-c" {
+			dev, _ := GetVolumeDevPath(vol)
+			mounts[dev] = []string{"/mnt/" + name}
+		}
+	}
+
+	defer func(run func(*exec.Cmd) error, get func(string) ([]string, error)) {
+		runCommand, getMounts = run, get
+	}(runCommand, getMounts)
+	getMounts = func(dev string) ([]string, error) {
+		return mounts[dev], nil
+	}
+
+	tests := map[string]struct {
+		existing    []string
+		failCreate  string
+		failFreeze  string
+		failSuspend string
+		wantCalls   []string
+		wantSnaps   []string
+		wantErr     bool
+	}{
+		"freezes all the volumes around the snapshots": {
+			wantCalls: []string{
+				"freeze /mnt/pvc-a", "freeze /mnt/pvc-b", "suspend lvmvg-pvc--c",
+				"lvcreate group-pvc-a", "lvcreate group-pvc-b", "lvcreate group-pvc-c",
+				"unfreeze /mnt/pvc-a", "unfreeze /mnt/pvc-b", "resume lvmvg-pvc--c",
+			},
+			wantSnaps: []string{"group-pvc-a", "group-pvc-b", "group-pvc-c"},
+		},
+		"skips the existing snapshots": {
+			existing: []string{"group-pvc-a"},
+			wantCalls: []string{
+				"freeze /mnt/pvc-a", "freeze /mnt/pvc-b", "suspend lvmvg-pvc--c",
+				"lvcreate group-pvc-b", "lvcreate group-pvc-c",
+				"unfreeze /mnt/pvc-a", "unfreeze /mnt/pvc-b", "resume lvmvg-pvc--c",
+			},
+			wantSnaps: []string{"group-pvc-a", "group-pvc-b", "group-pvc-c"},
+		},
+		"removes the snapshots of the group on failure": {
+			failCreate: "group-pvc-b",
+			wantCalls: []string{
+				"freeze /mnt/pvc-a", "freeze /mnt/pvc-b", "suspend lvmvg-pvc--c",
+				"lvcreate group-pvc-a", "lvcreate group-pvc-b",
+				"lvremove group-pvc-a",
+				"unfreeze /mnt/pvc-a", "unfreeze /mnt/pvc-b", "resume lvmvg-pvc--c",
+			},
+			wantErr: true,
+		},
+		"thaws the frozen volumes on freeze failure": {
+			failFreeze: "/mnt/pvc-b",
+			wantCalls: []string{
+				"freeze /mnt/pvc-a", "freeze /mnt/pvc-b",
+				"unfreeze /mnt/pvc-a",
+			},
+			wantErr: true,
+		},
+		"thaws the frozen volumes on suspend failure": {
+			failSuspend: "lvmvg-pvc--c",
+			wantCalls: []string{
+				"freeze /mnt/pvc-a", "freeze /mnt/pvc-b", "suspend lvmvg-pvc--c",
+				"unfreeze /mnt/pvc-a", "unfreeze /mnt/pvc-b",
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeLVM{
+				snaps:       map[string]bool{},
+				failCreate:  tt.failCreate,
+				failFreeze:  tt.failFreeze,
+				failSuspend: tt.failSuspend,
+			}
+			for _, snap := range tt.existing {
+				fake.snaps[snap] = true
+			}
+			runCommand = fake.run
+
+			err := CreateSnapshotGroup(vols, snaps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateSnapshotGroup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.wantCalls) {
+				t.Errorf("CreateSnapshotGroup() ran %v, want %v", fake.calls, tt.wantCalls)
+			}
+			var gotSnaps []string
+			for _, snap := range snaps {
+				if fake.snaps[snap.Name] {
+					gotSnaps = append(gotSnaps, snap.Name)
+				}
+			}
+			if !reflect.DeepEqual(gotSnaps, tt.wantSnaps) {
+				t.Errorf("CreateSnapshotGroup() left snapshots %v, want %v", gotSnaps, tt.wantSnaps)
+			}
+		})
+	}
+}
@@ -20,9 +20,13 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"os/exec"
+	"regexp"
 	"strconv"
 
+	"github.com/openebs/lib-csi/pkg/btrfs"
 	"github.com/openebs/lib-csi/pkg/device/iolimit"
+	"github.com/openebs/lib-csi/pkg/xfs"
 
 	mnt "github.com/openebs/lib-csi/pkg/mount"
 	"google.golang.org/grpc/codes"
@@ -130,7 +134,47 @@ func UmountVolume(vol *apis.LVMVolume, targetPath string,
 	return nil
 }
 
-func verifyMountRequest(vol *apis.LVMVolume, mountpath string) (bool, error) {
+// isReadOnly checks if the volume is to be mounted read-only.
+func isReadOnly(mountInfo *MountInfo) bool {
+	for _, opt := range mountInfo.MountOptions {
+		if opt == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// getMountOptions returns the options of the mount point.
+func getMountOptions(mountPath string) ([]string, error) {
+	mounter := mount.New("")
+	mountList, err := mounter.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, mntInfo := range mountList {
+		if mntInfo.Path == mountPath {
+			return mntInfo.Opts, nil
+		}
+	}
+	return nil, nil
+}
+
+// areReadOnlyMounts checks if all the given mount points are read-only.
+func areReadOnlyMounts(mountPaths []string) (bool, error) {
+	for _, mp := range mountPaths {
+		opts, err := getMountOptions(mp)
+		if err != nil {
+			return false, err
+		}
+		if !isReadOnly(&MountInfo{MountOptions: opts}) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func verifyMountRequest(vol *apis.LVMVolume, mountInfo *MountInfo) (bool, error) {
+	mountpath := mountInfo.MountPath
 	if len(mountpath) == 0 {
 		return false, status.Error(codes.InvalidArgument, "verifyMount: mount path missing in request")
 	}
@@ -170,8 +214,17 @@ func verifyMountRequest(vol *apis.LVMVolume, mountpath string) (bool, error) {
 			}
 		}
 
+		// a volume can be mounted read-only at more than one path
+		// as long as none of its mounts is writable.
+		readOnly := isReadOnly(mountInfo)
+		if readOnly {
+			if readOnly, err = areReadOnlyMounts(currentMounts); err != nil {
+				return false, status.Errorf(codes.Internal, "verifyMount: get mount options failed %s", err.Error())
+			}
+		}
+
 		// if it is not a shared volume, then it should not mounted to more than one path
-		if vol.Spec.Shared != "yes" {
+		if vol.Spec.Shared != "yes" && !readOnly {
 			klog.Errorf(
 				"can not mount, volume:%s already mounted dev %s mounts: %v",
 				vol.Name, devicePath, currentMounts,
@@ -184,8 +237,8 @@ func verifyMountRequest(vol *apis.LVMVolume, mountpath string) (bool, error) {
 
 // MountVolume mounts the disk to the specified path
 func MountVolume(vol *apis.LVMVolume, mount *MountInfo, podLVInfo *PodLVInfo) error {
-	volume := vol.Spec.VolGroup + "/" + vol.Name
-	mounted, err := verifyMountRequest(vol, mount.MountPath)
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
+	mounted, err := verifyMountRequest(vol, mount)
 	if err != nil {
 		return err
 	}
@@ -197,6 +250,16 @@ func MountVolume(vol *apis.LVMVolume, mount *MountInfo, podLVInfo *PodLVInfo) er
 
 	devicePath := DevPath + volume
 
+	if hasContentSource(vol) {
+		if err = generateFilesystemUUID(vol, devicePath, mount.FSType); err != nil {
+			return status.Errorf(
+				codes.Internal,
+				"failed to generate filesystem uuid for the volume error: %s",
+				err.Error(),
+			)
+		}
+	}
+
 	err = FormatAndMountVol(devicePath, mount)
 	if err != nil {
 		return status.Errorf(
@@ -208,6 +271,18 @@ func MountVolume(vol *apis.LVMVolume, mount *MountInfo, podLVInfo *PodLVInfo) er
 
 	klog.Infof("lvm: volume %v mounted %v fs %v", volume, mount.MountPath, mount.FSType)
 
+	// volume can be larger than the source it has been created from,
+	// grow the filesystem to fill the whole volume.
+	if hasContentSource(vol) {
+		if err = growFilesystem(devicePath, mount); err != nil {
+			return status.Errorf(
+				codes.Internal,
+				"failed to grow the filesystem of the volume error: %s",
+				err.Error(),
+			)
+		}
+	}
+
 	if ioLimitsEnabled && podLVInfo != nil {
 		if err := setIOLimits(vol, podLVInfo, devicePath); err != nil {
 			klog.Warningf("lvm: error setting io limits: podUid %s, device %s, err=%v", podLVInfo.UID, devicePath, err)
@@ -219,6 +294,62 @@ func MountVolume(vol *apis.LVMVolume, mount *MountInfo, podLVInfo *PodLVInfo) er
 	return nil
 }
 
+// hasContentSource checks if the volume has been created with the
+// content of some other volume or snapshot.
+func hasContentSource(vol *apis.LVMVolume) bool {
+	return vol.Spec.SnapName != "" || vol.Spec.SourceVolume != ""
+}
+
+// generateFilesystemUUID generates a new filesystem UUID for the volume
+// having a content source, as the filesystem is a copy of its source and
+// xfs won't mount two filesystems having the same UUID. It is done only
+// when the volume is not mounted anywhere.
+func generateFilesystemUUID(vol *apis.LVMVolume, devicePath string, fsType string) error {
+	if fsType != "xfs" && fsType != "btrfs" {
+		return nil
+	}
+
+	mapperPath, err := GetVolumeDevPath(vol)
+	if err != nil {
+		return err
+	}
+	currentMounts, err := mnt.GetMounts(mapperPath)
+	if err != nil {
+		return err
+	}
+	if len(currentMounts) != 0 {
+		return nil
+	}
+
+	if fsType == "xfs" {
+		return xfs.GenerateUUID(devicePath)
+	}
+	return btrfs.GenerateUUID(devicePath)
+}
+
+// growFilesystem expands the filesystem of the mounted volume
+// to the size of the volume.
+func growFilesystem(devicePath string, mountInfo *MountInfo) error {
+	var cmd *exec.Cmd
+	switch mountInfo.FSType {
+	case "xfs":
+		cmd = exec.Command("xfs_growfs", mountInfo.MountPath)
+	case "btrfs":
+		return btrfs.ResizeBTRFS(mountInfo.MountPath)
+	default:
+		// ext4 is the default filesystem if fs type is not provided
+		cmd = exec.Command("resize2fs", devicePath)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		klog.Errorf("lvm: failed to grow filesystem on %s cmd %v error: %s",
+			devicePath, cmd.Args, string(out))
+		return err
+	}
+	return nil
+}
+
 // MountFilesystem mounts the disk to the specified path
 func MountFilesystem(vol *apis.LVMVolume, mount *MountInfo, podinfo *PodLVInfo) error {
 	if err := os.MkdirAll(mount.MountPath, 0755); err != nil {
@@ -231,10 +362,13 @@ func MountFilesystem(vol *apis.LVMVolume, mount *MountInfo, podinfo *PodLVInfo)
 // MountBlock mounts the block disk to the specified path
 func MountBlock(vol *apis.LVMVolume, mountinfo *MountInfo, podLVInfo *PodLVInfo) error {
 	target := mountinfo.MountPath
-	volume := vol.Spec.VolGroup + "/" + vol.Name
+	volume := vol.Spec.VolGroup + "/" + getLVName(vol)
 	devicePath := DevPath + volume
 
 	mountopt := []string{"bind"}
+	if isReadOnly(mountinfo) {
+		mountopt = append(mountopt, "ro")
+	}
 
 	mounter := &mount.SafeFormatAndMount{Interface: mount.New(""), Exec: utilexec.New()}
 
@@ -268,33 +402,94 @@ func setIOLimits(vol *apis.LVMVolume, podLVInfo *PodLVInfo, devicePath string) e
 	if podLVInfo == nil {
 		return errors.New("PodLVInfo is missing. Skipping setting IOLimits")
 	}
-	capacityBytes, err := strconv.ParseUint(vol.Spec.Capacity, 10, 64)
+	ioMax, err := getIOMax(vol, podLVInfo.LVGroup)
 	if err != nil {
 		klog.Warning("error parsing LVMVolume.Spec.Capacity. Skipping setting IOLimits", err)
 		return err
 	}
-	capacityGB := uint64(math.Ceil(float64(capacityBytes) / (1024 * 1024 * 1024)))
-	klog.Infof("Capacity of device in GB: %v", capacityGB)
-	riops := GetRIopsPerGB(podLVInfo.LVGroup) * capacityGB
-	wiops := GetWIopsPerGB(podLVInfo.LVGroup) * capacityGB
-	rbps := GetRBpsPerGB(podLVInfo.LVGroup) * capacityGB
-	wbps := GetWBpsPerGB(podLVInfo.LVGroup) * capacityGB
 	klog.Infof("Setting iolimits for podUId %s, device %s: riops=%v, wiops=%v, rbps=%v, wbps=%v",
-		podLVInfo.UID, devicePath, riops, wiops, rbps, wbps,
+		podLVInfo.UID, devicePath, ioMax.Riops, ioMax.Wiops, ioMax.Rbps, ioMax.Wbps,
 	)
-	err = iolimit.SetIOLimits(&iolimit.Request{
+	return iolimit.SetIOLimits(&iolimit.Request{
 		DeviceName:       devicePath,
 		PodUid:           podLVInfo.UID,
 		ContainerRuntime: getContainerRuntime(),
-		IOLimit: &iolimit.IOMax{
-			Riops: riops,
-			Wiops: wiops,
-			Rbps:  rbps,
-			Wbps:  wbps,
-		},
+		IOLimit:          ioMax,
 	})
+}
+
+// getIOMax returns the IO limits of the volume derived from the per GB
+// rates of the volume group. The limits set on the volume take precedence
+// over the rates, only for the limits which are set.
+func getIOMax(vol *apis.LVMVolume, vgName string) (*iolimit.IOMax, error) {
+	capacityBytes, err := strconv.ParseUint(vol.Spec.Capacity, 10, 64)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	capacityGB := uint64(math.Ceil(float64(capacityBytes) / (1024 * 1024 * 1024)))
+	klog.Infof("Capacity of device in GB: %v", capacityGB)
+	ioMax := &iolimit.IOMax{
+		Riops: GetRIopsPerGB(vgName) * capacityGB,
+		Wiops: GetWIopsPerGB(vgName) * capacityGB,
+		Rbps:  GetRBpsPerGB(vgName) * capacityGB,
+		Wbps:  GetWBpsPerGB(vgName) * capacityGB,
+	}
+	limits := vol.Spec.IOLimits
+	if limits == nil {
+		return ioMax, nil
+	}
+	if limits.ReadIOPS != 0 {
+		ioMax.Riops = limits.ReadIOPS
+	}
+	if limits.WriteIOPS != 0 {
+		ioMax.Wiops = limits.WriteIOPS
+	}
+	if limits.ReadBPS != 0 {
+		ioMax.Rbps = limits.ReadBPS
+	}
+	if limits.WriteBPS != 0 {
+		ioMax.Wbps = limits.WriteBPS
+	}
+	return ioMax, nil
+}
+
+// podTargetPath matches the target paths where the kubelet publishes the
+// volumes for a pod, <kubelet>/pods/<uid>/volumes/kubernetes.io~csi/<pv>/mount
+// for the filesystem volumes and
+// <kubelet>/plugins/kubernetes.io/csi/volumeDevices/publish/<pv>/<uid>
+// for the block volumes.
+var podTargetPath = regexp.MustCompile(
+	`/pods/([^/]+)/volumes/kubernetes\.io~csi/[^/]+/mount$|/volumeDevices/publish/[^/]+/([^/]+)$`)
+
+// getPodUID returns the uid of the pod the volume
+// is published for at the target path.
+func getPodUID(targetPath string) (string, bool) {
+	match := podTargetPath.FindStringSubmatch(targetPath)
+	if match == nil {
+		return "", false
+	}
+	return match[1] + match[2], true
+}
+
+// ApplyIOLimits sets the IO limits of the volume for the pods it is
+// published for, so that the limits modified after the volume has been
+// published take effect without publishing it again.
+func ApplyIOLimits(vol *apis.LVMVolume) error {
+	if !ioLimitsEnabled {
+		return nil
+	}
+	devicePath := DevPath + vol.Spec.VolGroup + "/" + getLVName(vol)
+	for _, targetPath := range vol.Status.PublishedPaths {
+		uid, ok := getPodUID(targetPath)
+		if !ok {
+			klog.Warningf("lvm: no pod uid in the target path %s of volume %s, skipping setting io limits",
+				targetPath, vol.Name)
+			continue
+		}
+		podLVInfo := &PodLVInfo{UID: uid, LVGroup: vol.Spec.VolGroup}
+		if err := setIOLimits(vol, podLVInfo, devicePath); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -18,16 +18,19 @@ import (
 	"context"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 
 	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
 	"github.com/openebs/lvm-localpv/pkg/builder/snapbuilder"
+	"github.com/openebs/lvm-localpv/pkg/builder/snapgroupbuilder"
 	"github.com/openebs/lvm-localpv/pkg/builder/volbuilder"
 )
 
@@ -44,8 +47,28 @@ const (
 	VolGroupKey string = "openebs.io/volgroup"
 	// LVMVolKey for the LVMSnapshot CR to store Persistence Volume name
 	LVMVolKey string = "openebs.io/persistent-volume"
+	// LVMRollbackKey is the annotation on the LVMVolume CR to request
+	// the rollback of the volume to the given LVMSnapshot
+	LVMRollbackKey string = "openebs.io/rollback-snapshot"
+	// LVMSnapGroupKey for the LVMSnapshot CR to store the name of the
+	// LVMSnapshotGroup it is a member of
+	LVMSnapGroupKey string = "openebs.io/snapshot-group"
+	// LVMSpreadGroupKey is the label on the LVMVolume CR storing the hash
+	// of the group of volumes it is spread from, across the nodes
+	LVMSpreadGroupKey string = "openebs.io/spread-group"
+	// LVMPVCNameKey is the annotation on the LVMVolume CR
+	// to store the name of its PVC
+	LVMPVCNameKey string = "openebs.io/pvc-name"
+	// LVMPVCNamespaceKey is the annotation on the LVMVolume CR
+	// to store the namespace of its PVC
+	LVMPVCNamespaceKey string = "openebs.io/pvc-namespace"
 	// LVMNodeKey will be used to insert Label in LVMVolume CR
 	LVMNodeKey string = "kubernetes.io/nodename"
+	// LVMDriverKey is the label on the CRs of a driver instance
+	// having a name other than the default driver name
+	LVMDriverKey string = "openebs.io/driver-name"
+	// DefaultDriverName is the default name of the CSI driver
+	DefaultDriverName string = "local.csi.openebs.io"
 	// LVMTopologyKey is supported topology key for the lvm driver
 	LVMTopologyKey string = "openebs.io/nodename"
 	// LVMStatusPending shows object has not handled yet
@@ -54,10 +77,20 @@ const (
 	LVMStatusFailed string = "Failed"
 	// LVMStatusReady shows object has been processed
 	LVMStatusReady string = "Ready"
+	// LVMStatusMerging shows the snapshot is being merged into its volume
+	LVMStatusMerging string = "Merging"
+	// LVMStatusMerged shows the snapshot has been merged into its volume
+	LVMStatusMerged string = "Merged"
+	// LVMStatusCompleted shows the volume rollback has been completed
+	LVMStatusCompleted string = "Completed"
 	// OpenEBSCasTypeKey for the cas-type label
 	OpenEBSCasTypeKey string = "openebs.io/cas-type"
 	// LVMCasTypeName for the name of the cas-type
 	LVMCasTypeName string = "localpv-lvm"
+	// DeletionPolicyBlock rejects the deletion of a volume having snapshots
+	DeletionPolicyBlock string = "Block"
+	// DeletionPolicyCascade deletes the snapshots along with the volume
+	DeletionPolicyCascade string = "Cascade"
 )
 
 var (
@@ -69,6 +102,10 @@ var (
 
 	// GoogleAnalyticsEnabled should send google analytics or not
 	GoogleAnalyticsEnabled string
+
+	// DriverName is the name of the CSI driver instance, the CRs
+	// of the other driver instances are ignored by this instance.
+	DriverName = DefaultDriverName
 )
 
 func init() {
@@ -85,6 +122,43 @@ func init() {
 	GoogleAnalyticsEnabled = os.Getenv(GoogleAnalyticsKey)
 }
 
+// DriverLabels returns the labels to be set on the CRs created by this
+// driver instance. The CRs of the default driver instance are not
+// labeled, so that the CRs created by the older versions of the driver
+// keep belonging to it.
+func DriverLabels() map[string]string {
+	if DriverName == DefaultDriverName {
+		return nil
+	}
+	return map[string]string{LVMDriverKey: DriverName}
+}
+
+// DriverLabelSelector returns the label selector matching
+// the CRs of this driver instance.
+func DriverLabelSelector() string {
+	if DriverName == DefaultDriverName {
+		return "!" + LVMDriverKey
+	}
+	return LVMDriverKey + "=" + DriverName
+}
+
+// GetLVMNodeName returns the name of the LVMNode CR of this
+// driver instance for the given node.
+func GetLVMNodeName(nodeID string) string {
+	if DriverName == DefaultDriverName {
+		return nodeID
+	}
+	return nodeID + "." + DriverName
+}
+
+// GetLVMNodeID returns the id of the node of the given LVMNode CR.
+func GetLVMNodeID(node *apis.LVMNode) string {
+	if nodeID, ok := node.Labels[LVMNodeKey]; ok {
+		return nodeID
+	}
+	return node.Name
+}
+
 // ProvisionVolume creates a LVMVolume CR,
 // watcher for volume is present in CSI agent
 func ProvisionVolume(vol *apis.LVMVolume) (*apis.LVMVolume, error) {
@@ -201,6 +275,32 @@ func UpdateVolInfo(vol *apis.LVMVolume, state string) error {
 	return err
 }
 
+// RescheduleVolume moves the failed LVMVolume to the given node to be
+// provisioned again, recording the failed attempt in its status. The
+// volume group is reset if the node changes, unless the volume is
+// restored or cloned, as it can only be created in the volume group
+// of its source.
+func RescheduleVolume(vol *apis.LVMVolume, node string) (*apis.LVMVolume, error) {
+	newVol := vol.DeepCopy()
+	newVol.Status.Attempts = append(newVol.Status.Attempts, apis.VolumeAttempt{
+		Node:  vol.Spec.OwnerNodeID,
+		Error: vol.Status.Error,
+		Time:  metav1.Now(),
+	})
+	if node != vol.Spec.OwnerNodeID &&
+		vol.Spec.SnapName == "" && vol.Spec.SourceVolume == "" {
+		newVol.Spec.VolGroup = ""
+	}
+	newVol.Spec.OwnerNodeID = node
+	newVol.Status.State = LVMStatusPending
+	newVol.Status.Error = nil
+	newVol.Status.Progress = 0
+	// the node agent labels the volume with its node once processed
+	delete(newVol.Labels, LVMNodeKey)
+
+	return volbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newVol)
+}
+
 // UpdateVolGroup updates LVMVolume CR with volGroup name.
 func UpdateVolGroup(vol *apis.LVMVolume, vgName string) (*apis.LVMVolume, error) {
 	newVol, err := volbuilder.BuildFrom(vol).
@@ -211,6 +311,123 @@ func UpdateVolGroup(vol *apis.LVMVolume, vgName string) (*apis.LVMVolume, error)
 	return volbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newVol)
 }
 
+// ModifyVolume updates LVMVolume CR with the mutable properties of the
+// volume, which are then applied by the node agent.
+func ModifyVolume(vol *apis.LVMVolume, permission, errorWhenFull string,
+	limits *apis.VolumeIOLimits) error {
+	newVol, err := volbuilder.BuildFrom(vol).
+		WithPermission(permission).
+		WithErrorWhenFull(errorWhenFull).
+		WithIOLimits(limits).Build()
+	if err != nil {
+		return err
+	}
+	_, err = volbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newVol)
+	return err
+}
+
+// IsRollbackRequested checks if the rollback of the volume to the snapshot
+// set in its rollback annotation is yet to be completed.
+func IsRollbackRequested(vol *apis.LVMVolume) bool {
+	snapName := vol.Annotations[LVMRollbackKey]
+	if snapName == "" {
+		return false
+	}
+	rollback := vol.Status.Rollback
+	return rollback == nil || rollback.Snapshot != snapName ||
+		rollback.State == LVMStatusPending || rollback.State == LVMStatusMerging
+}
+
+// ErrorWhenFullConflict returns the name of a volume sharing the thin pool
+// of the volume whose errorWhenFull differs from the given one, or an empty
+// name if there is none. The errorWhenFull applies to the whole thin pool,
+// so the volumes of a pool having different ones would keep changing it.
+func ErrorWhenFullConflict(vol *apis.LVMVolume, errorWhenFull string,
+	vols []*apis.LVMVolume) string {
+	if errorWhenFull == "" || strings.TrimSpace(vol.Spec.ThinProvision) != YES {
+		return ""
+	}
+	for _, other := range vols {
+		if other.Name != vol.Name &&
+			other.Spec.OwnerNodeID == vol.Spec.OwnerNodeID &&
+			other.Spec.VolGroup == vol.Spec.VolGroup &&
+			strings.TrimSpace(other.Spec.ThinProvision) == YES &&
+			other.Spec.ErrorWhenFull != "" &&
+			other.Spec.ErrorWhenFull != errorWhenFull &&
+			other.DeletionTimestamp == nil {
+			return other.Name
+		}
+	}
+	return ""
+}
+
+// UpdateVolProgress updates LVMVolume CR with the percentage of the
+// content copied from its source snapshot or volume.
+func UpdateVolProgress(vol *apis.LVMVolume, percent int32) (*apis.LVMVolume, error) {
+	newVol, err := volbuilder.BuildFrom(vol).
+		WithProgress(percent).Build()
+	if err != nil {
+		return nil, err
+	}
+	return volbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newVol)
+}
+
+// UpdateVolCondition updates LVMVolume CR with the condition of the volume.
+func UpdateVolCondition(vol *apis.LVMVolume, cond *apis.VolumeCondition) error {
+	newVol, err := volbuilder.BuildFrom(vol).
+		WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+	_, err = volbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newVol)
+	return err
+}
+
+// UpdateVolPublishedPath adds the target path to the published paths of
+// the LVMVolume CR, or removes it once the volume is unpublished from it.
+// The volume is fetched again on conflicts, as the volumes may be published
+// for several pods at the same time.
+func UpdateVolPublishedPath(volumeID, targetPath string, published bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		vol, err := GetLVMVolume(volumeID)
+		if err != nil {
+			return err
+		}
+		var paths []string
+		found := false
+		for _, path := range vol.Status.PublishedPaths {
+			if path == targetPath {
+				found = true
+				continue
+			}
+			paths = append(paths, path)
+		}
+		if found == published {
+			return nil
+		}
+		if published {
+			paths = append(paths, targetPath)
+		}
+		newVol, err := volbuilder.BuildFrom(vol).
+			WithPublishedPaths(paths).Build()
+		if err != nil {
+			return err
+		}
+		_, err = volbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newVol)
+		return err
+	})
+}
+
+// UpdateVolRollback updates LVMVolume CR with the progress of its rollback.
+func UpdateVolRollback(vol *apis.LVMVolume, rollback *apis.VolumeRollback) (*apis.LVMVolume, error) {
+	newVol, err := volbuilder.BuildFrom(vol).
+		WithRollback(rollback).Build()
+	if err != nil {
+		return nil, err
+	}
+	return volbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newVol)
+}
+
 // RemoveVolFinalizer adds finalizer to LVMVolume CR
 func RemoveVolFinalizer(vol *apis.LVMVolume) error {
 	vol.Finalizers = nil
@@ -263,6 +480,28 @@ func GetSnapshotForVolume(volumeID string) (*apis.LVMSnapshotList, error) {
 	return snapList, err
 }
 
+// WaitForLVMSnapshotDestroy waits till the lvm snapshot gets deleted.
+func WaitForLVMSnapshotDestroy(ctx context.Context, snapName string) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+		_, err := GetLVMSnapshot(snapName)
+		if err != nil {
+			if k8serror.IsNotFound(err) {
+				return nil
+			}
+			return status.Errorf(codes.Aborted,
+				"lvm: destroy wait failed, not able to get the snapshot %s %s", snapName, err.Error())
+		}
+		timer.Reset(1 * time.Second)
+	}
+}
+
 // GetLVMSnapshotStatus returns the status of LVMSnapshot
 func GetLVMSnapshotStatus(snapID string) (string, error) {
 	getOptions := metav1.GetOptions{}
@@ -285,21 +524,36 @@ func UpdateSnapInfo(snap *apis.LVMSnapshot) error {
 		return nil
 	}
 
+	creationTime, originSize, err := GetSnapshotInfo(snap)
+	if err != nil {
+		return err
+	}
+
 	newSnap, err := snapbuilder.BuildFrom(snap).
 		WithFinalizer(finalizers).
 		WithLabels(labels).Build()
 
-	newSnap.Status.State = LVMStatusReady
-
 	if err != nil {
 		klog.Errorf("Update snapshot failed %s err: %s", snap.Name, err.Error())
 		return err
 	}
 
+	newSnap.Status.State = LVMStatusReady
+	newSnap.Status.CreationTime = &metav1.Time{Time: creationTime}
+	newSnap.Status.OriginSize = strconv.FormatInt(originSize, 10)
+
 	_, err = snapbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newSnap)
 	return err
 }
 
+// UpdateSnapStatus updates the state of LVMSnapshot CR
+func UpdateSnapStatus(snap *apis.LVMSnapshot, state string) error {
+	snap.Status.State = state
+
+	_, err := snapbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(snap)
+	return err
+}
+
 // RemoveSnapFinalizer adds finalizer to LVMSnapshot CR
 func RemoveSnapFinalizer(snap *apis.LVMSnapshot) error {
 	snap.Finalizers = nil
@@ -307,3 +561,52 @@ func RemoveSnapFinalizer(snap *apis.LVMSnapshot) error {
 	_, err := snapbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(snap)
 	return err
 }
+
+// UpdateSnapGroupStatus updates the state of LVMSnapshotGroup CR along
+// with its member snapshots or the error occurred while creating them
+func UpdateSnapGroupStatus(group *apis.LVMSnapshotGroup, state string,
+	snapshots []string, message string) error {
+	newGroup, err := snapgroupbuilder.BuildFrom(group).
+		WithSnapGroupStatus(state).
+		WithSnapshots(snapshots).
+		WithError(message).Build()
+
+	if err != nil {
+		klog.Errorf("Update snapshot group failed %s err: %s", group.Name, err.Error())
+		return err
+	}
+
+	_, err = snapgroupbuilder.NewKubeclient().WithNamespace(LvmNamespace).Update(newGroup)
+	return err
+}
+
+// ProvisionSnapshotGroup creates a LVMSnapshotGroup CR
+func ProvisionSnapshotGroup(group *apis.LVMSnapshotGroup) error {
+	_, err := snapgroupbuilder.NewKubeclient().WithNamespace(LvmNamespace).Create(group)
+	if err == nil {
+		klog.Infof("provisioned snapshot group %s", group.Name)
+	}
+	return err
+}
+
+// DeleteSnapshotGroup deletes the LVMSnapshotGroup CR, its member
+// LVMSnapshots are garbage collected along with it
+func DeleteSnapshotGroup(groupName string) error {
+	err := snapgroupbuilder.NewKubeclient().WithNamespace(LvmNamespace).Delete(groupName)
+	if err == nil {
+		klog.Infof("deprovisioned snapshot group %s", groupName)
+	}
+	return err
+}
+
+// GetLVMSnapshotGroup fetches the given LVM snapshot group
+func GetLVMSnapshotGroup(groupName string) (*apis.LVMSnapshotGroup, error) {
+	getOptions := metav1.GetOptions{}
+	return snapgroupbuilder.NewKubeclient().WithNamespace(LvmNamespace).Get(groupName, getOptions)
+}
+
+// GetSnapGroupMemberName returns the name of the member
+// LVMSnapshot of the group for the given volume
+func GetSnapGroupMemberName(groupName, volName string) string {
+	return groupName + "-" + volName
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+)
+
+func TestDriverScope(t *testing.T) {
+	defer func(name string) { DriverName = name }(DriverName)
+
+	tests := map[string]struct {
+		driverName string
+		labels     map[string]string
+		selector   string
+		nodeName   string
+	}{
+		"default driver": {
+			driverName: DefaultDriverName,
+			selector:   "!openebs.io/driver-name",
+			nodeName:   "node-1",
+		},
+		"other driver": {
+			driverName: "nvme.csi.openebs.io",
+			labels:     map[string]string{LVMDriverKey: "nvme.csi.openebs.io"},
+			selector:   "openebs.io/driver-name=nvme.csi.openebs.io",
+			nodeName:   "node-1.nvme.csi.openebs.io",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			DriverName = tt.driverName
+			if got := DriverLabels(); !reflect.DeepEqual(got, tt.labels) {
+				t.Errorf("DriverLabels() = %v, want %v", got, tt.labels)
+			}
+			if got := DriverLabelSelector(); got != tt.selector {
+				t.Errorf("DriverLabelSelector() = %v, want %v", got, tt.selector)
+			}
+			nodeName := GetLVMNodeName("node-1")
+			if nodeName != tt.nodeName {
+				t.Errorf("GetLVMNodeName() = %v, want %v", nodeName, tt.nodeName)
+			}
+			node := &apis.LVMNode{ObjectMeta: metav1.ObjectMeta{
+				Name:   nodeName,
+				Labels: map[string]string{LVMNodeKey: "node-1"},
+			}}
+			if got := GetLVMNodeID(node); got != "node-1" {
+				t.Errorf("GetLVMNodeID() = %v, want node-1", got)
+			}
+		})
+	}
+
+	// lvm nodes created by the older versions of the driver are not labeled
+	legacy := &apis.LVMNode{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	if got := GetLVMNodeID(legacy); got != "node-1" {
+		t.Errorf("GetLVMNodeID() = %v, want node-1", got)
+	}
+}
+
+func TestErrorWhenFullConflict(t *testing.T) {
+	volume := func(name, node, vg, thin, errorWhenFull string) *apis.LVMVolume {
+		return &apis.LVMVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: apis.VolumeInfo{OwnerNodeID: node, VolGroup: vg,
+				ThinProvision: thin, ErrorWhenFull: errorWhenFull},
+		}
+	}
+	vol := volume("pvc-1", "node-1", "lvmvg", YES, "yes")
+	// the volumes of the other pools, or not setting errorWhenFull
+	others := func(vols ...*apis.LVMVolume) []*apis.LVMVolume {
+		return append([]*apis.LVMVolume{
+			vol,
+			volume("pvc-3", "node-2", "lvmvg", YES, "no"),
+			volume("pvc-4", "node-1", "othervg", YES, "no"),
+			volume("pvc-5", "node-1", "lvmvg", "no", "no"),
+			volume("pvc-6", "node-1", "lvmvg", YES, ""),
+		}, vols...)
+	}
+
+	tests := map[string]struct {
+		vols          []*apis.LVMVolume
+		errorWhenFull string
+		want          string
+	}{
+		"same as the pool":      {vols: others(volume("pvc-2", "node-1", "lvmvg", YES, "yes")), errorWhenFull: "yes"},
+		"other pools":           {vols: others(), errorWhenFull: "no"},
+		"differs from the pool": {vols: others(volume("pvc-2", "node-1", "lvmvg", YES, "yes")), errorWhenFull: "no", want: "pvc-2"},
+		"not set":               {vols: others(volume("pvc-2", "node-1", "lvmvg", YES, "yes"))},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ErrorWhenFullConflict(vol, tt.errorWhenFull, tt.vols); got != tt.want {
+				t.Errorf("ErrorWhenFullConflict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRollbackRequested(t *testing.T) {
+	tests := map[string]struct {
+		snapshot string
+		rollback *apis.VolumeRollback
+		want     bool
+	}{
+		"not requested":       {},
+		"requested":           {snapshot: "snap-1", want: true},
+		"pending":             {snapshot: "snap-1", rollback: &apis.VolumeRollback{Snapshot: "snap-1", State: LVMStatusPending}, want: true},
+		"merging":             {snapshot: "snap-1", rollback: &apis.VolumeRollback{Snapshot: "snap-1", State: LVMStatusMerging}, want: true},
+		"completed":           {snapshot: "snap-1", rollback: &apis.VolumeRollback{Snapshot: "snap-1", State: LVMStatusCompleted}},
+		"failed":              {snapshot: "snap-1", rollback: &apis.VolumeRollback{Snapshot: "snap-1", State: LVMStatusFailed}},
+		"other snapshot done": {snapshot: "snap-2", rollback: &apis.VolumeRollback{Snapshot: "snap-1", State: LVMStatusCompleted}, want: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vol := &apis.LVMVolume{Status: apis.VolStatus{Rollback: tt.rollback}}
+			if tt.snapshot != "" {
+				vol.Annotations = map[string]string{LVMRollbackKey: tt.snapshot}
+			}
+			if got := IsRollbackRequested(vol); got != tt.want {
+				t.Errorf("IsRollbackRequested() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -39,7 +39,17 @@ import (
 )
 
 func (c *NodeController) listLVMVolumeGroup() ([]apis.VolumeGroup, error) {
-	return lvm.ListLVMVolumeGroup(true)
+	vgs, err := lvm.ListLVMVolumeGroup(true)
+	if err != nil {
+		return nil, err
+	}
+
+	lvs, err := lvm.ListLVMLogicalVolume()
+	if err != nil {
+		return nil, err
+	}
+	lvm.SetThinPools(vgs, lvs)
+	return vgs, nil
 }
 
 // syncHandler compares the actual state with the desired, and attempts to
@@ -79,8 +89,11 @@ func (c *NodeController) syncNode(namespace string, name string) error {
 	}
 
 	if node == nil { // if it doesn't exists, create lvm node object
+		labels := map[string]string{lvm.LVMNodeKey: lvm.NodeID}
 		if node, err = nodebuilder.NewBuilder().
 			WithNamespace(namespace).WithName(name).
+			WithLabels(labels).
+			WithLabels(lvm.DriverLabels()).
 			WithVolumeGroups(vgs).
 			WithOwnerReferences(c.ownerRef).
 			Build(); err != nil {
@@ -196,9 +209,10 @@ func (c *NodeController) deleteNode(obj interface{}) {
 // string which is then put onto the work queue. This method should *not* be
 // passed resources of any type other than LVMNode.
 func (c *NodeController) enqueueNode(node *apis.LVMNode) {
-	// node must exists in openebs namespace & must equal to the node id.
+	// node must exists in openebs namespace & must equal to the
+	// lvm node name of the node id.
 	if node.Namespace != lvm.LvmNamespace ||
-		node.Name != lvm.NodeID {
+		node.Name != lvm.GetLVMNodeName(lvm.NodeID) {
 		klog.Warningf("skipping lvm node object %s/%s", node.Namespace, node.Name)
 		return
 	}
@@ -246,7 +260,7 @@ func (c *NodeController) Run(threadiness int, stopCh <-chan struct{}) error {
 			klog.Info("Shutting down Node controller")
 			return nil
 		}
-		item := lvm.LvmNamespace + "/" + lvm.NodeID
+		item := lvm.LvmNamespace + "/" + lvm.GetLVMNodeName(lvm.NodeID)
 		c.workqueue.Add(item) // add the item to worker queue.
 		timer.Reset(c.pollInterval)
 	}
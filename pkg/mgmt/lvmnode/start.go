@@ -57,7 +57,8 @@ func Start(controllerMtx *sync.RWMutex, stopCh <-chan struct{}, pollInterval int
 	// setup watch only on node we are interested in.
 	nodeInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(openebsClientNew, 5*time.Minute,
 		lvm.LvmNamespace, func(options *metav1.ListOptions) {
-			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", lvm.NodeID).String()
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", lvm.GetLVMNodeName(lvm.NodeID)).String()
+			options.LabelSelector = lvm.DriverLabelSelector()
 		})
 
 	k8sNode, err := kubeClient.CoreV1().Nodes().Get(context.TODO(), lvm.NodeID, metav1.GetOptions{})
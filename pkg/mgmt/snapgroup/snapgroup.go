@@ -0,0 +1,366 @@
+/*
+Copyright 2021 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapgroup
+
+import (
+	"fmt"
+	"time"
+
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtimenew "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/builder/snapbuilder"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// isDeletionCandidate checks if a lvm snapshot group is a deletion candidate.
+func (c *SnapGroupController) isDeletionCandidate(group *apis.LVMSnapshotGroup) bool {
+	return group.ObjectMeta.DeletionTimestamp != nil
+}
+
+// isPending checks if the snapshots of a lvm snapshot group are yet to
+// be created. A group created without any status is also pending.
+func (c *SnapGroupController) isPending(group *apis.LVMSnapshotGroup) bool {
+	return group.Status.State == "" || group.Status.State == lvm.LVMStatusPending
+}
+
+// syncHandler compares the actual state with the desired, and attempts to
+// converge the two.
+func (c *SnapGroupController) syncHandler(key string) error {
+	// Convert the namespace/name string into a distinct namespace and name
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	// Get the snap group resource with this namespace/name
+	unstructuredGroup, err := c.groupLister.Namespace(namespace).Get(name)
+	if k8serror.IsNotFound(err) {
+		runtime.HandleError(fmt.Errorf("lvm snapshot group '%s' has been deleted", key))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	group := apis.LVMSnapshotGroup{}
+	err = runtimenew.DefaultUnstructuredConverter.FromUnstructured(unstructuredGroup.UnstructuredContent(), &group)
+	if err != nil {
+		klog.Infof("err %s, While converting unstructured obj to typed object\n", err.Error())
+	}
+	groupCopy := group.DeepCopy()
+	err = c.syncSnapGroup(groupCopy)
+	return err
+}
+
+// enqueueSnapGroup takes a LVMSnapshotGroup resource and converts it into a
+// namespace/name string which is then put onto the work queue. This method
+// should *not* be passed resources of any type other than LVMSnapshotGroup.
+func (c *SnapGroupController) enqueueSnapGroup(obj interface{}) {
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+// syncSnapGroup is the function which tries to converge to a desired state
+// for the LVMSnapshotGroup. The member LVMSnapshots are owned by the group,
+// so they get garbage collected along with it and nothing is done here on
+// its deletion.
+func (c *SnapGroupController) syncSnapGroup(group *apis.LVMSnapshotGroup) error {
+	if c.isDeletionCandidate(group) || !c.isPending(group) {
+		return nil
+	}
+
+	vols, err := getMemberVolumes(group)
+	if err != nil {
+		klog.Errorf("lvm: snapshot group %s can not be created: %v", group.Name, err)
+		return lvm.UpdateSnapGroupStatus(group, lvm.LVMStatusFailed, nil, err.Error())
+	}
+
+	snaps, err := provisionMemberSnapshots(group, vols)
+	if err != nil {
+		return err
+	}
+
+	if err = lvm.CreateSnapshotGroup(vols, snaps); err != nil {
+		klog.Errorf("lvm: could not create snapshot group %s: %v", group.Name, err)
+		// the snapshots have been removed, remove their CRs as well
+		for _, snap := range snaps {
+			if derr := lvm.DeleteSnapshot(snap.Name); derr != nil && !k8serror.IsNotFound(derr) {
+				return derr
+			}
+		}
+		return lvm.UpdateSnapGroupStatus(group, lvm.LVMStatusFailed, nil, err.Error())
+	}
+
+	var names []string
+	for _, snap := range snaps {
+		if err = lvm.UpdateSnapInfo(snap); err != nil {
+			return err
+		}
+		names = append(names, snap.Name)
+	}
+
+	return lvm.UpdateSnapGroupStatus(group, lvm.LVMStatusReady, names, "")
+}
+
+// getMemberVolumes fetches the member volumes of the group and verifies
+// that all of them are ready on the node where the group is created.
+func getMemberVolumes(group *apis.LVMSnapshotGroup) ([]*apis.LVMVolume, error) {
+	if len(group.Spec.Volumes) == 0 {
+		return nil, fmt.Errorf("no member volumes specified")
+	}
+
+	var vols []*apis.LVMVolume
+	seen := make(map[string]bool)
+	for _, name := range group.Spec.Volumes {
+		if seen[name] {
+			return nil, fmt.Errorf("volume %s specified more than once", name)
+		}
+		seen[name] = true
+
+		vol, err := lvm.GetLVMVolume(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get volume %s: %v", name, err)
+		}
+		if vol.Spec.OwnerNodeID != group.Spec.OwnerNodeID {
+			return nil, fmt.Errorf("volume %s is present on node %s, not on %s",
+				name, vol.Spec.OwnerNodeID, group.Spec.OwnerNodeID)
+		}
+		if vol.Status.State != lvm.LVMStatusReady || vol.DeletionTimestamp != nil {
+			return nil, fmt.Errorf("volume %s is not ready", name)
+		}
+		vols = append(vols, vol)
+	}
+	return vols, nil
+}
+
+// provisionMemberSnapshots creates the member LVMSnapshot CRs of the group,
+// one for each member volume, owned by the group. The CRs are created
+// without any state so that the snapshot controller does not pick them up,
+// the group controller marks them ready once all the snapshots are created.
+func provisionMemberSnapshots(group *apis.LVMSnapshotGroup, vols []*apis.LVMVolume) ([]*apis.LVMSnapshot, error) {
+	ownerRef := metav1.OwnerReference{
+		APIVersion: apis.SchemeGroupVersion.String(),
+		Kind:       "LVMSnapshotGroup",
+		Name:       group.Name,
+		UID:        group.UID,
+	}
+
+	var snaps []*apis.LVMSnapshot
+	for _, vol := range vols {
+		name := lvm.GetSnapGroupMemberName(group.Name, vol.Name)
+
+		snap, err := lvm.GetLVMSnapshot(name)
+		if err == nil {
+			snaps = append(snaps, snap)
+			continue
+		}
+		if !k8serror.IsNotFound(err) {
+			return nil, err
+		}
+
+		labels := map[string]string{
+			lvm.LVMVolKey:       vol.Name,
+			lvm.LVMSnapGroupKey: group.Name,
+		}
+
+		builder := snapbuilder.NewBuilder().
+			WithName(name).
+			WithLabels(labels).
+			WithLabels(lvm.DriverLabels()).
+			WithOwnerNode(vol.Spec.OwnerNodeID).
+			WithVolGroup(vol.Spec.VolGroup).
+			WithOwnerReference(ownerRef)
+
+		// thin snapshots are allocated from the thin pool, thick
+		// snapshots reserve the full capacity of the volume
+		if vol.Spec.ThinProvision != lvm.YES {
+			builder = builder.WithSnapSize(vol.Spec.Capacity)
+		}
+
+		snap, err = builder.Build()
+		if err != nil {
+			return nil, err
+		}
+
+		if err = lvm.ProvisionSnapshot(snap); err != nil {
+			return nil, err
+		}
+
+		// fetch the created object, it is updated later on
+		if snap, err = lvm.GetLVMSnapshot(name); err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+// addSnapGroup is the add event handler for LVMSnapshotGroup
+func (c *SnapGroupController) addSnapGroup(obj interface{}) {
+	group, ok := c.getStructuredObject(obj)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("Couldn't get snapshot group object %#v", obj))
+		return
+	}
+
+	if lvm.NodeID != group.Spec.OwnerNodeID {
+		return
+	}
+	klog.Infof("Got add event for Snapshot group %s", group.Name)
+	c.enqueueSnapGroup(group)
+}
+
+// updateSnapGroup is the update event handler for LVMSnapshotGroup
+func (c *SnapGroupController) updateSnapGroup(oldObj, newObj interface{}) {
+	newGroup, ok := c.getStructuredObject(newObj)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("Couldn't get snapshot group object %#v", newGroup))
+		return
+	}
+
+	if lvm.NodeID != newGroup.Spec.OwnerNodeID {
+		return
+	}
+
+	// update on Snapshot group CR does not make sense unless it is still pending
+	if c.isPending(newGroup) {
+		klog.Infof("Got update event for Snapshot group %s", newGroup.Name)
+		c.enqueueSnapGroup(newGroup)
+	}
+}
+
+// Obj from queue is not readily in lvmsnapshotgroup type. This function would convert obj into lvmsnapshotgroup type.
+func (c *SnapGroupController) getStructuredObject(obj interface{}) (*apis.LVMSnapshotGroup, bool) {
+	unstructuredInterface, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("couldnt type assert obj: %#v to unstructured obj", obj))
+		return nil, false
+	}
+	group := &apis.LVMSnapshotGroup{}
+	err := runtimenew.DefaultUnstructuredConverter.FromUnstructured(unstructuredInterface.UnstructuredContent(), &group)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("err %s, While converting unstructured obj to typed object\n", err.Error()))
+		return nil, false
+	}
+	return group, true
+}
+
+// Run will set up the event handlers for types we are interested in, as well
+// as syncing informer caches and starting workers. It will block until stopCh
+// is closed, at which point it will shutdown the workqueue and wait for
+// workers to finish processing their current work items.
+func (c *SnapGroupController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	// Start the informer factories to begin populating the informer caches
+	klog.Info("Starting SnapGroup controller")
+
+	// Wait for the k8s caches to be synced before starting workers
+	klog.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(stopCh, c.groupSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+	klog.Info("Starting SnapGroup workers")
+	// Launch worker to process SnapGroup resources
+	// Threadiness will decide the number of workers you want to launch to process work items from queue
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+
+	klog.Info("Started SnapGroup workers")
+	<-stopCh
+	klog.Info("Shutting down SnapGroup workers")
+
+	return nil
+}
+
+// runWorker is a long-running function that will continually call the
+// processNextWorkItem function in order to read and process a message on the
+// workqueue.
+func (c *SnapGroupController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+// processNextWorkItem will read a single work item off the workqueue and
+// attempt to process it, by calling the syncHandler.
+func (c *SnapGroupController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+
+	if shutdown {
+		return false
+	}
+
+	// We wrap this block in a func so we can defer c.workqueue.Done.
+	err := func(obj interface{}) error {
+		// We call Done here so the workqueue knows we have finished
+		// processing this item. We also must remember to call Forget if we
+		// do not want this work item being re-queued. For example, we do
+		// not call Forget if a transient error occurs, instead the item is
+		// put back on the workqueue and attempted again after a back-off
+		// period.
+		defer c.workqueue.Done(obj)
+		var key string
+		var ok bool
+		// We expect strings to come off the workqueue. These are of the
+		// form namespace/name. We do this as the delayed nature of the
+		// workqueue means the items in the informer cache may actually be
+		// more up to date that when the item was initially put onto the
+		// workqueue.
+		if key, ok = obj.(string); !ok {
+			// As the item in the workqueue is actually invalid, we call
+			// Forget here else we'd go into a loop of attempting to
+			// process a work item that is invalid.
+			c.workqueue.Forget(obj)
+			runtime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
+			return nil
+		}
+		// Run the syncHandler, passing it the namespace/name string of the
+		// SnapGroup resource to be synced.
+		if err := c.syncHandler(key); err != nil {
+			// Put the item back on the workqueue to handle any transient errors.
+			c.workqueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
+		}
+		// Finally, if no error occurs we Forget this item so it does not
+		// get queued again until another change happens.
+		c.workqueue.Forget(obj)
+		klog.Infof("Successfully synced '%s'", key)
+		return nil
+	}(obj)
+
+	if err != nil {
+		runtime.HandleError(err)
+		return true
+	}
+
+	return true
+}
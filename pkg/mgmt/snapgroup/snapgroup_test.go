@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapgroup
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	apis "github.com/openebs/lvm-localpv/pkg/apis/openebs.io/lvm/v1alpha1"
+	"github.com/openebs/lvm-localpv/pkg/lvm"
+)
+
+// newTestSnapshotServer serves the LVMSnapshot requests of the default
+// clients with an in memory set of snapshots, returning the set.
+func newTestSnapshotServer(t *testing.T, existing ...apis.LVMSnapshot) map[string]*apis.LVMSnapshot {
+	var mu sync.Mutex
+	snaps := make(map[string]*apis.LVMSnapshot)
+	for i := range existing {
+		snaps[existing[i].Name] = &existing[i]
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		var obj interface{}
+		code := http.StatusOK
+		switch r.Method {
+		case http.MethodGet:
+			name := path.Base(r.URL.Path)
+			snap, ok := snaps[name]
+			if !ok {
+				status := k8serror.NewNotFound(schema.GroupResource{
+					Group: apis.SchemeGroupVersion.Group, Resource: "lvmsnapshots"}, name).ErrStatus
+				obj, code = &status, http.StatusNotFound
+				break
+			}
+			obj = snap
+		case http.MethodPost:
+			snap := &apis.LVMSnapshot{}
+			if !assert.NoError(t, json.NewDecoder(r.Body).Decode(snap)) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			snaps[snap.Name] = snap
+			obj, code = snap, http.StatusCreated
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(code)
+		assert.NoError(t, json.NewEncoder(w).Encode(obj))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENEBS_IO_K8S_MASTER", srv.URL)
+
+	namespace := lvm.LvmNamespace
+	lvm.LvmNamespace = "openebs"
+	t.Cleanup(func() { lvm.LvmNamespace = namespace })
+	return snaps
+}
+
+func TestProvisionMemberSnapshots(t *testing.T) {
+	group := &apis.LVMSnapshotGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "group", UID: "group-uid"},
+		Spec: apis.LVMSnapshotGroupSpec{
+			OwnerNodeID: "node-1",
+			Volumes:     []string{"pvc-thick", "pvc-thin"},
+		},
+	}
+	vols := []*apis.LVMVolume{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-thick"},
+			Spec: apis.VolumeInfo{
+				OwnerNodeID: "node-1", VolGroup: "lvmvg", Capacity: "1073741824",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-thin"},
+			Spec: apis.VolumeInfo{
+				OwnerNodeID: "node-1", VolGroup: "lvmvg", Capacity: "1073741824", ThinProvision: lvm.YES,
+			},
+		},
+	}
+
+	t.Run("creates the member snapshots", func(t *testing.T) {
+		created := newTestSnapshotServer(t)
+
+		snaps, err := provisionMemberSnapshots(group, vols)
+		assert.NoError(t, err)
+		if !assert.Len(t, snaps, 2) {
+			return
+		}
+		assert.Equal(t, "group-pvc-thick", snaps[0].Name)
+		assert.Equal(t, "group-pvc-thin", snaps[1].Name)
+		assert.Len(t, created, 2)
+
+		for _, snap := range snaps {
+			assert.Equal(t, group.Name, snap.Labels[lvm.LVMSnapGroupKey])
+			assert.Equal(t, "node-1", snap.Spec.OwnerNodeID)
+			assert.Equal(t, "lvmvg", snap.Spec.VolGroup)
+			assert.Empty(t, snap.Status.State, "the snapshot controller must not pick them up")
+			if assert.Len(t, snap.OwnerReferences, 1) {
+				assert.Equal(t, "LVMSnapshotGroup", snap.OwnerReferences[0].Kind)
+				assert.Equal(t, group.Name, snap.OwnerReferences[0].Name)
+				assert.Equal(t, group.UID, snap.OwnerReferences[0].UID)
+			}
+		}
+		assert.Equal(t, "pvc-thick", snaps[0].Labels[lvm.LVMVolKey])
+		assert.Equal(t, "1073741824", snaps[0].Spec.SnapSize, "thick snapshots reserve the volume capacity")
+		assert.Equal(t, "pvc-thin", snaps[1].Labels[lvm.LVMVolKey])
+		assert.Empty(t, snaps[1].Spec.SnapSize, "thin snapshots are allocated from the pool")
+	})
+
+	t.Run("reuses the existing member snapshots", func(t *testing.T) {
+		existing := apis.LVMSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "group-pvc-thick",
+				Labels: map[string]string{lvm.LVMVolKey: "pvc-thick", "existing": "true"},
+			},
+		}
+		created := newTestSnapshotServer(t, existing)
+
+		snaps, err := provisionMemberSnapshots(group, vols)
+		assert.NoError(t, err)
+		if assert.Len(t, snaps, 2) {
+			assert.Equal(t, "true", snaps[0].Labels["existing"])
+			assert.Equal(t, "group-pvc-thin", snaps[1].Name)
+		}
+		assert.Len(t, created, 2)
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	runtimenew "k8s.io/apimachinery/pkg/runtime"
@@ -93,7 +94,14 @@ func (c *SnapController) syncSnap(snap *apis.LVMSnapshot) error {
 		// if the status of the snapshot resource is Pending, then
 		// we create the snapshot on the machine
 		if snap.Status.State == lvm.LVMStatusPending {
-			err = lvm.CreateSnapshot(snap)
+			var vol *apis.LVMVolume
+			vol, err = lvm.GetLVMVolume(snap.Labels[lvm.LVMVolKey])
+			if err == nil {
+				if err = lvm.CreateSnapshot(vol, snap); err != nil {
+					lvm.RecordSnapshotEvent(c.recorder, snap, vol, corev1.EventTypeWarning,
+						lvm.EventReasonSnapshotFailed, err.Error())
+				}
+			}
 			if err == nil {
 				err = lvm.UpdateSnapInfo(snap)
 			}
@@ -78,8 +78,9 @@ func newVolController(kubeClient kubernetes.Interface, client dynamic.Interface,
 	dynInformer dynamicinformer.DynamicSharedInformerFactory) (*VolController, error) {
 	//Creating informer for lvmvolume resource
 	volInformer := dynInformer.ForResource(volresource).Informer()
-	//This ratelimiter requeues failed items after 5 secs for first 12 attempts. Then objects are requeued after 30 secs.
-	rateLimiter := workqueue.NewItemFastSlowRateLimiter(5*time.Second, 30*time.Second, 12)
+	// This ratelimiter requeues failed items with an exponential backoff,
+	// starting from 2 secs and doubling after each attempt up to 2 mins.
+	rateLimiter := workqueue.NewItemExponentialFailureRateLimiter(2*time.Second, 2*time.Minute)
 
 	klog.Infof("Creating event broadcaster")
 	eventBroadcaster := record.NewBroadcaster()
@@ -21,12 +21,15 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
+
+	"github.com/openebs/lvm-localpv/pkg/lvm"
 )
 
 var (
@@ -54,7 +57,11 @@ func Start(controllerMtx *sync.RWMutex, stopCh <-chan struct{}) error {
 		return errors.Wrap(err, "error building dynamic client for lvmvolume cr")
 	}
 
-	VolInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(openebsClient, 5*time.Minute)
+	// watch only the resources of this driver instance.
+	VolInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(openebsClient, 5*time.Minute,
+		metav1.NamespaceAll, func(options *metav1.ListOptions) {
+			options.LabelSelector = lvm.DriverLabelSelector()
+		})
 	// Build() fn of all controllers calls AddToScheme to adds all types of this
 	// clientset into the given scheme.
 	// If multiple controllers happen to call this AddToScheme same time,
@@ -18,14 +18,17 @@ package volume
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	runtimenew "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -36,6 +39,21 @@ import (
 	"github.com/openebs/lvm-localpv/pkg/lvm"
 )
 
+const (
+	// volumeHealthCheckInterval is the interval at which the health of the
+	// volumes provisioned on the node is checked.
+	volumeHealthCheckInterval = time.Minute
+
+	// rollbackPollInterval is the interval at which the progress of the
+	// rollback of a volume is checked.
+	rollbackPollInterval = 10 * time.Second
+
+	// maxTransientRetries is the number of times the provisioning of a
+	// volume is retried with backoff on a transient error, before the
+	// volume is marked failed.
+	maxTransientRetries = 5
+)
+
 // isDeletionCandidate checks if a lvm volume is a deletion candidate.
 func (c *VolController) isDeletionCandidate(Vol *apis.LVMVolume) bool {
 	return Vol.ObjectMeta.DeletionTimestamp != nil
@@ -102,6 +120,25 @@ func (c *VolController) updateVol(oldObj, newObj interface{}) {
 	if c.isDeletionCandidate(newVol) {
 		klog.Infof("Got update event for deleted Vol %s, Deletion timestamp %s", newVol.Name, newVol.ObjectMeta.DeletionTimestamp)
 		c.enqueueVol(newVol)
+		return
+	}
+
+	// the properties of a provisioned volume might have been modified
+	// or its rollback to a snapshot might have been requested
+	oldVol, ok := c.getStructuredObject(oldObj)
+	if ok && newVol.Status.State == lvm.LVMStatusReady &&
+		(!reflect.DeepEqual(oldVol.Spec, newVol.Spec) ||
+			oldVol.Annotations[lvm.LVMRollbackKey] != newVol.Annotations[lvm.LVMRollbackKey]) {
+		klog.Infof("Got update event for modified Vol %s", newVol.Name)
+		c.enqueueVol(newVol)
+	}
+
+	// the failed volume might have been rescheduled on this node
+	if ok && newVol.Status.State == lvm.LVMStatusPending &&
+		(oldVol.Status.State != lvm.LVMStatusPending ||
+			oldVol.Spec.OwnerNodeID != newVol.Spec.OwnerNodeID) {
+		klog.Infof("Got update event for rescheduled Vol %s", newVol.Name)
+		c.enqueueVol(newVol)
 	}
 }
 
@@ -135,6 +172,17 @@ func (c *VolController) deleteVol(obj interface{}) {
 	c.enqueueVol(Vol)
 }
 
+// enqueueVolAfter puts the LVMVolume resource back onto the work queue
+// after the given duration.
+func (c *VolController) enqueueVolAfter(obj interface{}, duration time.Duration) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.workqueue.AddAfter(key, duration)
+}
+
 // enqueueVol takes a LVMVolume resource and converts it into a namespace/name
 // string which is then put onto the work queue. This method should *not* be
 // passed resources of any type other than LVMVolume.
@@ -184,7 +232,30 @@ func (c *VolController) syncVol(vol *apis.LVMVolume) error {
 		return nil
 	case lvm.LVMStatusReady:
 		klog.Info("lvm volume already provisioned")
-		return nil
+		if lvm.IsRollbackRequested(vol) {
+			return c.syncVolRollback(vol)
+		}
+		// clear the status of a finished rollback once its annotation
+		// is removed, so that the same snapshot can be requested again
+		if rollback := vol.Status.Rollback; rollback != nil && vol.Annotations[lvm.LVMRollbackKey] == "" &&
+			(rollback.State == lvm.LVMStatusCompleted || rollback.State == lvm.LVMStatusFailed) {
+			if vol, err = lvm.UpdateVolRollback(vol, nil); err != nil {
+				return err
+			}
+		}
+		// apply the properties which might have been modified
+		// after the volume has been provisioned.
+		if err = lvm.ModifyLVMVolume(c.withPoolErrorWhenFull(vol)); err != nil {
+			return err
+		}
+		return lvm.ApplyIOLimits(vol)
+	}
+
+	// volume restored from a snapshot or cloned from a volume can only be
+	// created in the volume group of its source, so there is no other vg
+	// to fall back to.
+	if vol.Spec.SnapName != "" || vol.Spec.SourceVolume != "" {
+		return c.syncVolContent(vol)
 	}
 
 	// if there is already a volGroup field set for lvmvolume resource,
@@ -196,15 +267,20 @@ func (c *VolController) syncVol(vol *apis.LVMVolume) error {
 		}
 	}
 
-	vgs, err := c.getVgPriorityList(vol)
+	vgs, matched, err := c.getVgPriorityList(vol)
 	if err != nil {
 		return err
 	}
 
+	var volErr *apis.VolumeError
 	if len(vgs) == 0 {
 		err = fmt.Errorf("no vg available to serve volume request having regex=%q & capacity=%q",
 			vol.Spec.VgPattern, vol.Spec.Capacity)
 		klog.Errorf("lvm volume %v - %v", vol.Name, err)
+		volErr = &apis.VolumeError{Code: apis.InsufficientCapacity, Message: err.Error()}
+		if !matched {
+			volErr.Code = apis.VGNotFound
+		}
 	} else {
 		for _, vg := range vgs {
 			// first update volGroup field in lvm volume resource for ensuring
@@ -217,44 +293,304 @@ func (c *VolController) syncVol(vol *apis.LVMVolume) error {
 				return lvm.UpdateVolInfo(vol, lvm.LVMStatusReady)
 			}
 		}
+		if c.retryVolume(vol, err) {
+			return err
+		}
+		volErr = c.transformLVMError(err)
 	}
 
 	// In case no vg available or lvm.CreateVolume fails for all vgs, mark
 	// the volume provisioning failed so that controller can reschedule it.
-	vol.Status.Error = c.transformLVMError(err)
+	return c.failVolume(vol, volErr)
+}
+
+// retryVolume checks if the provisioning of the volume should be retried
+// for the given error. The transient errors are retried with backoff,
+// until the volume has been retried maxTransientRetries times.
+func (c *VolController) retryVolume(vol *apis.LVMVolume, err error) bool {
+	if !lvm.IsTransientError(err) {
+		return false
+	}
+	key, kerr := cache.MetaNamespaceKeyFunc(vol)
+	if kerr != nil {
+		return false
+	}
+	retries := c.workqueue.NumRequeues(key)
+	if retries >= maxTransientRetries {
+		klog.Errorf("lvm volume %v - giving up after %d retries: %v", vol.Name, retries, err)
+		return false
+	}
+	klog.Warningf("lvm volume %v - retrying transient error (retry %d/%d): %v",
+		vol.Name, retries+1, maxTransientRetries, err)
+	return true
+}
+
+// failVolume marks the volume provisioning failed with the given error
+// and records the failure as an event on the volume and its PVC.
+func (c *VolController) failVolume(vol *apis.LVMVolume, volErr *apis.VolumeError) error {
+	lvm.RecordVolumeEvent(c.recorder, vol, corev1.EventTypeWarning,
+		lvm.VolumeErrorReason(volErr), volErr.Message)
+	vol.Status.Error = volErr
 	return lvm.UpdateVolInfo(vol, lvm.LVMStatusFailed)
 }
 
+// syncVolRollback reverts the volume to the snapshot set in its rollback
+// annotation by merging the snapshot into the volume. The merge is started
+// only once the volume is unpublished, and the volume is requeued until
+// the merge completes so that its progress is reported in the status.
+func (c *VolController) syncVolRollback(vol *apis.LVMVolume) error {
+	snapName := vol.Annotations[lvm.LVMRollbackKey]
+	rollback := &apis.VolumeRollback{Snapshot: snapName, State: lvm.LVMStatusPending}
+	if vol.Status.Rollback != nil && vol.Status.Rollback.Snapshot == snapName {
+		rollback = vol.Status.Rollback.DeepCopy()
+	}
+
+	snap, err := lvm.GetLVMSnapshot(snapName)
+	if k8serror.IsNotFound(err) {
+		return c.failVolRollback(vol, rollback, fmt.Sprintf("snapshot %s not found", snapName))
+	}
+	if err != nil {
+		return err
+	}
+
+	if rollback.State == lvm.LVMStatusMerging {
+		merged, err := lvm.IsSnapshotMerged(snap)
+		if err != nil {
+			return err
+		}
+		if merged {
+			klog.Infof("lvm: volume %s rolled back to snapshot %s", vol.Name, snapName)
+			if err = lvm.UpdateSnapStatus(snap, lvm.LVMStatusMerged); err != nil {
+				return err
+			}
+			rollback.State = lvm.LVMStatusCompleted
+			_, err = lvm.UpdateVolRollback(vol, rollback)
+			return err
+		}
+		// make sure the merge has been started, the node agent might
+		// have been restarted before starting it
+		if err = lvm.MergeSnapshot(vol, snap); err != nil {
+			// the merge may have completed since it was checked,
+			// removing the snapshot logical volume
+			if merged, mergedErr := lvm.IsSnapshotMerged(snap); mergedErr != nil || !merged {
+				return c.failVolRollback(vol, rollback, err.Error())
+			}
+			c.enqueueVol(vol)
+			return nil
+		}
+		c.enqueueVolAfter(vol, rollbackPollInterval)
+		return nil
+	}
+
+	if snap.Labels[lvm.LVMVolKey] != vol.Name {
+		return c.failVolRollback(vol, rollback,
+			fmt.Sprintf("snapshot %s is not a snapshot of volume %s", snapName, vol.Name))
+	}
+	if snap.Status.State != lvm.LVMStatusReady {
+		return c.failVolRollback(vol, rollback, fmt.Sprintf("snapshot %s is not ready", snapName))
+	}
+
+	open, err := lvm.IsVolumeOpen(vol)
+	if err != nil {
+		return err
+	}
+	if open {
+		if vol.Status.Rollback == nil || vol.Status.Rollback.Snapshot != snapName {
+			rollback.Message = "waiting for the volume to be unpublished"
+			if _, err = lvm.UpdateVolRollback(vol, rollback); err != nil {
+				return err
+			}
+		}
+		c.enqueueVolAfter(vol, rollbackPollInterval)
+		return nil
+	}
+
+	// record the merge before starting it, so that it is tracked
+	// even if the node agent restarts right after starting it
+	rollback.State = lvm.LVMStatusMerging
+	rollback.Message = ""
+	if vol, err = lvm.UpdateVolRollback(vol, rollback); err != nil {
+		return err
+	}
+	if err = lvm.MergeSnapshot(vol, snap); err != nil {
+		return c.failVolRollback(vol, rollback, err.Error())
+	}
+	c.enqueueVolAfter(vol, rollbackPollInterval)
+	return nil
+}
+
+// failVolRollback marks the rollback of the volume failed with the given
+// message, it is not retried unless another rollback is requested.
+func (c *VolController) failVolRollback(vol *apis.LVMVolume, rollback *apis.VolumeRollback, message string) error {
+	klog.Errorf("lvm: rollback of volume %s failed: %s", vol.Name, message)
+	rollback.State = lvm.LVMStatusFailed
+	rollback.Message = message
+	_, err := lvm.UpdateVolRollback(vol, rollback)
+	return err
+}
+
+// syncVolContent creates the lvm volume having the content of its source
+// snapshot or volume, updating the progress of the copy in the volume status.
+func (c *VolController) syncVolContent(vol *apis.LVMVolume) error {
+	var (
+		snap   *apis.LVMSnapshot
+		srcVol *apis.LVMVolume
+		source string
+		err    error
+	)
+
+	if vol.Spec.SnapName != "" {
+		source = "snapshot " + vol.Spec.SnapName
+		if snap, err = lvm.GetLVMSnapshot(vol.Spec.SnapName); err != nil {
+			return err
+		}
+	} else {
+		source = "volume " + vol.Spec.SourceVolume
+		if srcVol, err = lvm.GetLVMVolume(vol.Spec.SourceVolume); err != nil {
+			return err
+		}
+	}
+
+	progress := func(percent int32) {
+		if percent == vol.Status.Progress {
+			return
+		}
+		newVol, err := lvm.UpdateVolProgress(vol, percent)
+		if err != nil {
+			// progress is informational only, do not fail the copy for it
+			klog.Warningf("lvm volume %v - failed to update progress to %d%%: %v",
+				vol.Name, percent, err)
+			return
+		}
+		vol = newVol
+	}
+
+	if snap != nil {
+		err = lvm.CreateVolumeFromSnapshot(vol, snap, progress)
+	} else {
+		err = lvm.CreateVolumeClone(vol, srcVol, progress)
+	}
+	if err == nil {
+		return lvm.UpdateVolInfo(vol, lvm.LVMStatusReady)
+	}
+
+	klog.Errorf("lvm volume %v - failed to create from %v: %v", vol.Name, source, err)
+	if c.retryVolume(vol, err) {
+		return err
+	}
+	return c.failVolume(vol, c.transformLVMError(err))
+}
+
+// withPoolErrorWhenFull returns the volume without its errorWhenFull if it
+// differs from the one of another volume sharing its thin pool, so that the
+// thin pool is not changed back and forth by the sync of each volume.
+func (c *VolController) withPoolErrorWhenFull(vol *apis.LVMVolume) *apis.LVMVolume {
+	if vol.Spec.ErrorWhenFull == "" {
+		return vol
+	}
+	objs, err := c.VolLister.Namespace(lvm.LvmNamespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list lvm volumes: %v", err)
+		return vol
+	}
+	vols := make([]*apis.LVMVolume, 0, len(objs))
+	for _, obj := range objs {
+		if other, ok := c.getStructuredObject(obj); ok {
+			vols = append(vols, other)
+		}
+	}
+	other := lvm.ErrorWhenFullConflict(vol, vol.Spec.ErrorWhenFull, vols)
+	if other == "" {
+		return vol
+	}
+	klog.Warningf("not applying errorWhenFull %s of lvm volume %s, the volume %s sharing its thin pool has a different one",
+		vol.Spec.ErrorWhenFull, vol.Name, other)
+	vol = vol.DeepCopy()
+	vol.Spec.ErrorWhenFull = ""
+	return vol
+}
+
+// checkVolumesHealth updates the condition of the lvm volumes provisioned
+// on this node as per the state of their logical volumes, volume groups
+// and thin pools.
+func (c *VolController) checkVolumesHealth() {
+	lvs, err := lvm.ListLVMLogicalVolume()
+	if err != nil {
+		klog.Errorf("volume health: failed to list logical volumes: %v", err)
+		return
+	}
+	vgs, err := lvm.ListLVMVolumeGroup(false)
+	if err != nil {
+		klog.Errorf("volume health: failed to list volume groups: %v", err)
+		return
+	}
+
+	objs, err := c.VolLister.Namespace(lvm.LvmNamespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("volume health: failed to list lvm volumes: %v", err)
+		return
+	}
+
+	for _, obj := range objs {
+		vol, ok := c.getStructuredObject(obj)
+		if !ok {
+			continue
+		}
+		if vol.Spec.OwnerNodeID != lvm.NodeID ||
+			vol.Status.State != lvm.LVMStatusReady ||
+			c.isDeletionCandidate(vol) {
+			continue
+		}
+
+		cond := lvm.GetVolumeCondition(vol, lvs, vgs)
+		if reflect.DeepEqual(cond, vol.Status.Condition) {
+			continue
+		}
+		if cond.Abnormal {
+			klog.Warningf("volume health: lvm volume %s is abnormal: %s", vol.Name, cond.Message)
+		} else {
+			klog.Infof("volume health: lvm volume %s is healthy", vol.Name)
+		}
+		if err = lvm.UpdateVolCondition(vol, cond); err != nil {
+			klog.Errorf("volume health: failed to update condition of lvm volume %s: %v", vol.Name, err)
+		}
+	}
+}
+
 // getVgPriorityList returns ordered list of volume groups from higher to lower
 // priority to use for provisioning a lvm volume. As of now, we are prioritizing
-// the vg having least amount free space available to fit the volume.
-func (c *VolController) getVgPriorityList(vol *apis.LVMVolume) ([]apis.VolumeGroup, error) {
+// the vg having least amount free space available to fit the volume. The thin
+// volumes are prioritized by the usage of the thin pools instead. It also
+// returns whether any vg on the node matches the vg pattern of the volume.
+func (c *VolController) getVgPriorityList(vol *apis.LVMVolume) ([]apis.VolumeGroup, bool, error) {
 	re, err := regexp.Compile(vol.Spec.VgPattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regular expression %v for lvm volume %s: %v",
+		return nil, false, fmt.Errorf("invalid regular expression %v for lvm volume %s: %v",
 			vol.Spec.VgPattern, vol.Name, err)
 	}
 	capacity, err := strconv.Atoi(vol.Spec.Capacity)
 	if err != nil {
-		return nil, fmt.Errorf("invalid requested capacity %v for lvm volume %s: %v",
+		return nil, false, fmt.Errorf("invalid requested capacity %v for lvm volume %s: %v",
 			vol.Spec.Capacity, vol.Name, err)
 	}
 
 	vgs, err := lvm.ListLVMVolumeGroup(true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list vgs available on node: %v", err)
+		return nil, false, fmt.Errorf("failed to list vgs available on node: %v", err)
 	}
+	if vol.Spec.ThinProvision == lvm.YES {
+		return getThinVgPriorityList(vol, vgs, re)
+	}
+	matched := false
 	filteredVgs := make([]apis.VolumeGroup, 0)
 	for _, vg := range vgs {
 		if !re.MatchString(vg.Name) {
 			continue
 		}
-		// skip the vgs capacity comparison in case of thin provision enable volume
-		if vol.Spec.ThinProvision != "yes" {
-			// filter vgs having insufficient capacity.
-			if vg.Free.Value() < int64(capacity) {
-				continue
-			}
+		matched = true
+		// filter vgs having insufficient capacity.
+		if vg.Free.Value() < int64(capacity) {
+			continue
 		}
 		filteredVgs = append(filteredVgs, vg)
 	}
@@ -263,7 +599,75 @@ func (c *VolController) getVgPriorityList(vol *apis.LVMVolume) ([]apis.VolumeGro
 	sort.SliceStable(filteredVgs, func(i, j int) bool {
 		return filteredVgs[i].Free.Cmp(filteredVgs[j].Free) < 0
 	})
-	return filteredVgs, nil
+	return filteredVgs, matched, nil
+}
+
+// getThinVgPriorityList returns the volume groups where the thin volume can
+// be provisioned, from higher to lower priority. The volume groups whose thin
+// pool reached the usage threshold of the volume are filtered out, along with
+// the ones having no thin pool yet nor the free space to create it. The
+// volume group whose thin pool is the least used, and then has the least size
+// allocated relative to its size, is prioritized. The volume groups without
+// thin pool come first, as their pool is yet to be created.
+func getThinVgPriorityList(vol *apis.LVMVolume, vgs []apis.VolumeGroup,
+	re *regexp.Regexp) ([]apis.VolumeGroup, bool, error) {
+	lvs, err := lvm.ListLVMLogicalVolume()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list lvs available on node: %v", err)
+	}
+	lvm.SetThinPools(vgs, lvs)
+
+	threshold := float64(vol.Spec.ThinPoolThreshold)
+	if threshold == 0 {
+		threshold = 100
+	}
+
+	type candidate struct {
+		vg        apis.VolumeGroup
+		usage     float64
+		allocated float64
+	}
+	matched := false
+	candidates := make([]candidate, 0)
+	for _, vg := range vgs {
+		if !re.MatchString(vg.Name) {
+			continue
+		}
+		matched = true
+		pool := lvm.GetThinPool(&vg)
+		if pool == nil {
+			// the thin pool is created out of the free space of the vg.
+			if vg.Free.Value() <= lvm.MinExtentRoundOffSize {
+				continue
+			}
+			candidates = append(candidates, candidate{vg: vg})
+			continue
+		}
+		usage := lvm.ThinPoolUsage(pool)
+		if usage >= threshold {
+			klog.Infof("lvm volume %v - skipping thin pool %s/%s, its usage %.1f%% reached the threshold %v%%",
+				vol.Name, vg.Name, pool.Name, usage, threshold)
+			continue
+		}
+		var allocated float64
+		if size := pool.Size.Value(); size > 0 {
+			allocated = float64(pool.VirtualSize.Value()) / float64(size)
+		}
+		candidates = append(candidates, candidate{vg: vg, usage: usage, allocated: allocated})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].usage != candidates[j].usage {
+			return candidates[i].usage < candidates[j].usage
+		}
+		return candidates[i].allocated < candidates[j].allocated
+	})
+
+	filteredVgs := make([]apis.VolumeGroup, 0, len(candidates))
+	for _, c := range candidates {
+		filteredVgs = append(filteredVgs, c.vg)
+	}
+	return filteredVgs, matched, nil
 }
 
 func (c *VolController) transformLVMError(err error) *apis.VolumeError {
@@ -306,6 +710,8 @@ func (c *VolController) Run(threadiness int, stopCh <-chan struct{}) error {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
 
+	go wait.Until(c.checkVolumesHealth, volumeHealthCheckInterval, stopCh)
+
 	klog.Info("Started Vol workers")
 	<-stopCh
 	klog.Info("Shutting down Vol workers")
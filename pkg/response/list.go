@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+// ListVolumesResponseBuilder helps building an
+// instance of csi ListVolumesResponse
+type ListVolumesResponseBuilder struct {
+	response *csi.ListVolumesResponse
+}
+
+// NewListVolumesResponseBuilder returns a new
+// instance of ListVolumesResponseBuilder
+func NewListVolumesResponseBuilder() *ListVolumesResponseBuilder {
+	return &ListVolumesResponseBuilder{
+		response: &csi.ListVolumesResponse{},
+	}
+}
+
+// WithEntry adds a volume along with the ids of the nodes where
+// it is published and its condition against the ListVolumesResponse
+// instance
+func (b *ListVolumesResponseBuilder) WithEntry(volume *csi.Volume,
+	publishedNodeIDs []string, condition *csi.VolumeCondition) *ListVolumesResponseBuilder {
+	b.response.Entries = append(b.response.Entries,
+		&csi.ListVolumesResponse_Entry{
+			Volume: volume,
+			Status: &csi.ListVolumesResponse_VolumeStatus{
+				PublishedNodeIds: publishedNodeIDs,
+				VolumeCondition:  condition,
+			},
+		},
+	)
+	return b
+}
+
+// WithNextToken sets the token to fetch the next page
+// against the ListVolumesResponse instance
+func (b *ListVolumesResponseBuilder) WithNextToken(token string) *ListVolumesResponseBuilder {
+	b.response.NextToken = token
+	return b
+}
+
+// Build returns the constructed instance
+// of csi ListVolumesResponse
+func (b *ListVolumesResponseBuilder) Build() *csi.ListVolumesResponse {
+	return b.response
+}
+
+// ListSnapshotsResponseBuilder helps building an
+// instance of csi ListSnapshotsResponse
+type ListSnapshotsResponseBuilder struct {
+	response *csi.ListSnapshotsResponse
+}
+
+// NewListSnapshotsResponseBuilder returns a new
+// instance of ListSnapshotsResponseBuilder
+func NewListSnapshotsResponseBuilder() *ListSnapshotsResponseBuilder {
+	return &ListSnapshotsResponseBuilder{
+		response: &csi.ListSnapshotsResponse{},
+	}
+}
+
+// WithEntry adds a snapshot against the
+// ListSnapshotsResponse instance
+func (b *ListSnapshotsResponseBuilder) WithEntry(snapshot *csi.Snapshot) *ListSnapshotsResponseBuilder {
+	b.response.Entries = append(b.response.Entries,
+		&csi.ListSnapshotsResponse_Entry{
+			Snapshot: snapshot,
+		},
+	)
+	return b
+}
+
+// WithNextToken sets the token to fetch the next page
+// against the ListSnapshotsResponse instance
+func (b *ListSnapshotsResponseBuilder) WithNextToken(token string) *ListSnapshotsResponseBuilder {
+	b.response.NextToken = token
+	return b
+}
+
+// Build returns the constructed instance
+// of csi ListSnapshotsResponse
+func (b *ListSnapshotsResponseBuilder) Build() *csi.ListSnapshotsResponse {
+	return b.response
+}